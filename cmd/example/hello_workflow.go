@@ -34,7 +34,7 @@ func main() {
 				Config: map[string]interface{}{
 					"message": "Processando...",
 				},
-				Next: []string{"step3"},
+				Next: []models.Transition{{Step: "step3"}},
 			},
 			{
 				ID:   "step3",
@@ -5,15 +5,27 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
+	"github.com/carloskvasir/goflow/internal/connectors"
 	"github.com/carloskvasir/goflow/internal/core"
 	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/store"
+	"github.com/carloskvasir/goflow/internal/triggers"
 	"github.com/gin-gonic/gin"
 )
 
@@ -46,6 +58,9 @@ func loadEnv(filePath string) error {
 	return nil
 }
 
+// shutdownTimeout limita a espera pelas requisições em andamento ao encerrar o servidor
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	// Carregar variáveis de ambiente do arquivo .env
 	envFile := filepath.Join(".", ".env")
@@ -53,8 +68,36 @@ func main() {
 		log.Printf("Aviso: não foi possível carregar o arquivo .env: %v", err)
 	}
 
+	// Abrir o backend de persistência
+	workflowStore, err := openStore()
+	if err != nil {
+		log.Fatalf("Erro ao abrir o armazenamento: %v", err)
+	}
+
+	// Carregar as conexões globais
+	connections, err := loadConnections()
+	if err != nil {
+		log.Fatalf("Erro ao carregar conexões: %v", err)
+	}
+
 	// Criar engine de workflows
-	engine := core.NewWorkflowEngine()
+	engine := core.NewWorkflowEngine(
+		core.WithStore(workflowStore),
+		core.WithRunRetention(loadRunRetention()),
+		core.WithSecrets(loadSecrets()),
+		core.WithConnections(connections),
+	)
+	if err := engine.Load(); err != nil {
+		log.Fatalf("Erro ao carregar workflows: %v", err)
+	}
+
+	// Retomar execuções interrompidas por uma parada do servidor
+	for _, run := range engine.ResumeRuns() {
+		log.Printf("Retomando execução %s do workflow %s", run.ID, run.WorkflowID)
+	}
+
+	// Iniciar os agendamentos de workflows
+	engine.StartScheduler()
 
 	// Configurar router
 	router := setupRouter(engine)
@@ -65,15 +108,135 @@ func main() {
 		port = "3000"
 	}
 
-	// Iniciar servidor
-	if err := router.Run(":" + port); err != nil {
-		log.Fatalf("Erro ao iniciar servidor: %v", err)
+	// Iniciar servidor até receber SIGINT ou SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{Addr: ":" + port, Handler: router}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	var failed bool
+	select {
+	case err := <-serverErr:
+		log.Printf("Erro ao iniciar servidor: %v", err)
+		failed = true
+	case <-ctx.Done():
+		log.Println("Encerrando servidor")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Erro ao encerrar servidor: %v", err)
+		}
+	}
+
+	// O engine é fechado depois do servidor, para que nenhuma requisição use o armazenamento fechado
+	if err := engine.Close(); err != nil {
+		log.Printf("Erro ao fechar engine: %v", err)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// openStore cria o backend de persistência configurado em GOFLOW_STORE
+func openStore() (store.Store, error) {
+	switch backend := os.Getenv("GOFLOW_STORE"); backend {
+	case "", "memory":
+		return store.NewMemoryStore(), nil
+	case "bolt":
+		path := os.Getenv("GOFLOW_STORE_PATH")
+		if path == "" {
+			path = "goflow.db"
+		}
+		return store.NewBoltStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store backend: %s", backend)
 	}
 }
 
+// loadRunRetention lê a política de retenção de execuções das variáveis de ambiente
+func loadRunRetention() core.RunRetention {
+	retention := core.RunRetention{MaxRuns: core.DefaultMaxRuns}
+
+	if value := os.Getenv("GOFLOW_RUN_RETENTION_MAX_RUNS"); value != "" {
+		maxRuns, err := strconv.Atoi(value)
+		if err != nil {
+			log.Printf("Aviso: valor inválido para GOFLOW_RUN_RETENTION_MAX_RUNS: %v", err)
+		} else {
+			retention.MaxRuns = maxRuns
+		}
+	}
+
+	if value := os.Getenv("GOFLOW_RUN_RETENTION_MAX_AGE"); value != "" {
+		maxAge, err := time.ParseDuration(value)
+		if err != nil {
+			log.Printf("Aviso: valor inválido para GOFLOW_RUN_RETENTION_MAX_AGE: %v", err)
+		} else {
+			retention.MaxAge = maxAge
+		}
+	}
+
+	return retention
+}
+
+// loadSecrets lê os segredos das variáveis de ambiente GOFLOW_SECRET_<NOME>,
+// disponíveis nos templates como {{ secrets.<nome> }}
+func loadSecrets() map[string]string {
+	const prefix = "GOFLOW_SECRET_"
+
+	secrets := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || name == prefix {
+			continue
+		}
+		secrets[strings.ToLower(strings.TrimPrefix(name, prefix))] = value
+	}
+	return secrets
+}
+
+// loadConnections lê as conexões globais do arquivo JSON indicado em GOFLOW_CONNECTIONS
+func loadConnections() (map[string]connectors.Config, error) {
+	path := os.Getenv("GOFLOW_CONNECTIONS")
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading connections file: %w", err)
+	}
+
+	var connections map[string]connectors.Config
+	if err := json.Unmarshal(data, &connections); err != nil {
+		return nil, fmt.Errorf("error decoding connections file: %w", err)
+	}
+	return connections, nil
+}
+
+// respondExecutionError responde ao erro de uma execução, separando inputs inválidos
+func respondExecutionError(c *gin.Context, err error) {
+	var inputErr *core.InputError
+	if errors.As(err, &inputErr) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "errors": inputErr.Problems})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
 func setupRouter(engine *core.WorkflowEngine) *gin.Engine {
 	router := gin.Default()
 
+	// Webhooks que iniciam workflows a partir de sistemas externos
+	webhooks := triggers.NewWebhooks(engine)
+	router.POST("/hooks/*name", func(c *gin.Context) {
+		webhooks.Handle(c.Writer, c.Request, strings.TrimPrefix(c.Param("name"), "/"))
+	})
+
 	// Endpoints da API
 	api := router.Group("/api/v1")
 	{
@@ -87,7 +250,7 @@ func setupRouter(engine *core.WorkflowEngine) *gin.Engine {
 				return
 			}
 
-			c.JSON(http.StatusOK, workflow)
+			c.JSON(http.StatusOK, workflow.Redacted())
 		})
 
 		api.POST("/workflows", func(c *gin.Context) {
@@ -98,11 +261,36 @@ func setupRouter(engine *core.WorkflowEngine) *gin.Engine {
 			}
 
 			if err := engine.RegisterWorkflow(&workflow); err != nil {
+				var validationErrs core.ValidationErrors
+				if errors.As(err, &validationErrs) {
+					c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "errors": validationErrs})
+					return
+				}
 				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 				return
 			}
 
-			c.JSON(http.StatusCreated, workflow)
+			c.JSON(http.StatusCreated, workflow.Redacted())
+		})
+
+		api.POST("/workflows/validate", func(c *gin.Context) {
+			var workflow models.Workflow
+			if err := c.ShouldBindJSON(&workflow); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := engine.ValidateWorkflow(&workflow); err != nil {
+				var validationErrs core.ValidationErrors
+				if errors.As(err, &validationErrs) {
+					c.JSON(http.StatusUnprocessableEntity, gin.H{"valid": false, "errors": validationErrs})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+
+			c.JSON(http.StatusOK, gin.H{"valid": true})
 		})
 
 		api.DELETE("/workflows/:id", func(c *gin.Context) {
@@ -117,14 +305,136 @@ func setupRouter(engine *core.WorkflowEngine) *gin.Engine {
 		api.POST("/workflows/:id/execute", func(c *gin.Context) {
 			workflowID := c.Param("id")
 
-			result, err := engine.ExecuteWorkflow(c.Request.Context(), workflowID)
+			if _, exists := engine.GetWorkflow(workflowID); !exists {
+				c.JSON(http.StatusNotFound, gin.H{"error": "workflow not found"})
+				return
+			}
+
+			// O corpo da requisição, se houver, contém os inputs da execução
+			var inputs map[string]interface{}
+			body, err := io.ReadAll(c.Request.Body)
 			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if len(bytes.TrimSpace(body)) > 0 {
+				if err := json.Unmarshal(body, &inputs); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "inputs must be a JSON object: " + err.Error()})
+					return
+				}
+			}
+
+			// No modo assíncrono a execução roda em segundo plano e pode ser acompanhada por /runs/:runId
+			if async, _ := strconv.ParseBool(c.Query("async")); async {
+				run, err := engine.StartWorkflow(workflowID, inputs)
+				if err != nil {
+					respondExecutionError(c, err)
+					return
+				}
+
+				c.JSON(http.StatusAccepted, run)
+				return
+			}
+
+			result, err := engine.ExecuteWorkflowWithInputs(c.Request.Context(), workflowID, inputs)
+			if err != nil {
+				respondExecutionError(c, err)
 				return
 			}
 
 			c.JSON(http.StatusOK, result)
 		})
+
+		api.GET("/workflows/:id/runs", func(c *gin.Context) {
+			workflowID := c.Param("id")
+
+			if _, exists := engine.GetWorkflow(workflowID); !exists {
+				c.JSON(http.StatusNotFound, gin.H{"error": "workflow not found"})
+				return
+			}
+
+			c.JSON(http.StatusOK, engine.ListRuns(workflowID))
+		})
+
+		// Agendamentos
+		api.GET("/schedules", func(c *gin.Context) {
+			c.JSON(http.StatusOK, engine.ListSchedules())
+		})
+
+		api.GET("/workflows/:id/schedule", func(c *gin.Context) {
+			upcoming := 5
+			if value := c.Query("upcoming"); value != "" {
+				count, err := strconv.Atoi(value)
+				if err != nil || count < 0 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "invalid upcoming count"})
+					return
+				}
+				upcoming = count
+			}
+
+			status, exists := engine.GetSchedule(c.Param("id"), upcoming)
+			if !exists {
+				c.JSON(http.StatusNotFound, gin.H{"error": "schedule not found"})
+				return
+			}
+
+			c.JSON(http.StatusOK, status)
+		})
+
+		api.POST("/workflows/:id/schedule/pause", func(c *gin.Context) {
+			workflowID := c.Param("id")
+			if err := engine.PauseSchedule(workflowID); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+
+			status, _ := engine.GetSchedule(workflowID, 0)
+			c.JSON(http.StatusOK, status)
+		})
+
+		api.POST("/workflows/:id/schedule/resume", func(c *gin.Context) {
+			workflowID := c.Param("id")
+			if err := engine.ResumeSchedule(workflowID); err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+
+			status, _ := engine.GetSchedule(workflowID, 0)
+			c.JSON(http.StatusOK, status)
+		})
+
+		// Execuções
+		api.GET("/runs/:runId", func(c *gin.Context) {
+			run, exists := engine.GetRun(c.Param("runId"))
+			if !exists {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
+
+			c.JSON(http.StatusOK, run)
+		})
+
+		api.POST("/runs/:runId/cancel", func(c *gin.Context) {
+			runID := c.Param("runId")
+
+			if _, exists := engine.GetRun(runID); !exists {
+				c.JSON(http.StatusNotFound, gin.H{"error": "run not found"})
+				return
+			}
+
+			if err := engine.CancelRun(runID); err != nil {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+
+			run, _ := engine.GetRun(runID)
+			c.JSON(http.StatusAccepted, run)
+		})
+
+		// Circuit breakers das conexões
+		api.GET("/circuit-breakers", func(c *gin.Context) {
+			c.JSON(http.StatusOK, engine.CircuitBreakers())
+		})
 	}
 
 	return router
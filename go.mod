@@ -2,7 +2,13 @@ module github.com/carloskvasir/goflow
 
 go 1.22
 
-require github.com/gin-gonic/gin v1.9.1
+require (
+	github.com/expr-lang/expr v1.17.8
+	github.com/gin-gonic/gin v1.9.1
+	github.com/robfig/cron/v3 v3.0.1
+	github.com/santhosh-tekuri/jsonschema/v5 v5.3.1
+	go.etcd.io/bbolt v1.3.11
+)
 
 require (
 	github.com/bytedance/sonic v1.11.6 // indirect
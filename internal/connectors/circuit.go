@@ -0,0 +1,248 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package connectors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/retry"
+)
+
+// ErrCircuitOpen is returned, without calling the API, for requests made while
+// the circuit breaker of their connection or host is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Circuit breaker states
+const (
+	CircuitClosed   = "closed"    // Requests flow normally
+	CircuitOpen     = "open"      // Requests fail fast with ErrCircuitOpen
+	CircuitHalfOpen = "half_open" // A few probe requests test whether the API recovered
+)
+
+// Circuit breaker defaults
+const (
+	DefaultFailureThreshold = 5
+	DefaultOpenDuration     = 30 * time.Second
+	DefaultHalfOpenProbes   = 1
+)
+
+// CircuitBreakerConfig configures the circuit breaker of a connection. The
+// breaker opens after FailureThreshold consecutive failures, that is transport
+// errors, 429 and 5xx responses; after OpenDuration it lets HalfOpenProbes
+// requests through and closes once all of them succeed.
+type CircuitBreakerConfig struct {
+	FailureThreshold int           `json:"failure_threshold,omitempty"` // Default DefaultFailureThreshold
+	OpenDuration     time.Duration `json:"open_duration,omitempty"`     // Default DefaultOpenDuration
+	HalfOpenProbes   int           `json:"half_open_probes,omitempty"`  // Default DefaultHalfOpenProbes
+	Scope            string        `json:"scope,omitempty"`             // "connection" (default) or "host"
+}
+
+// CircuitStatus describes the state of a circuit breaker
+type CircuitStatus struct {
+	Key      string     `json:"key"`
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`            // Consecutive failures
+	OpenedAt *time.Time `json:"opened_at,omitempty"` // When the breaker last opened
+	RetryAt  *time.Time `json:"retry_at,omitempty"`  // When an open breaker lets probes through
+}
+
+// CircuitBreakers holds the circuit breakers of a set of connectors, keyed by
+// connection name or host, so that every connector calling the same API shares
+// the same breaker.
+type CircuitBreakers struct {
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+	now      func() time.Time
+}
+
+// NewCircuitBreakers creates an empty set of circuit breakers
+func NewCircuitBreakers() *CircuitBreakers {
+	return &CircuitBreakers{
+		breakers: make(map[string]*circuitBreaker),
+		now:      time.Now,
+	}
+}
+
+// breaker returns the breaker of a key, creating it with the given config
+func (b *CircuitBreakers) breaker(key string, config CircuitBreakerConfig) *circuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	breaker, exists := b.breakers[key]
+	if !exists {
+		breaker = newCircuitBreaker(key, config, b.now)
+		b.breakers[key] = breaker
+	}
+	return breaker
+}
+
+// Reset drops the breakers whose key starts with prefix, so that the next
+// request builds them again, closed, from the current config of its connection
+func (b *CircuitBreakers) Reset(prefix string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for key := range b.breakers {
+		if strings.HasPrefix(key, prefix) {
+			delete(b.breakers, key)
+		}
+	}
+}
+
+// Status returns the state of every breaker, ordered by key
+func (b *CircuitBreakers) Status() []CircuitStatus {
+	b.mu.Lock()
+	breakers := make([]*circuitBreaker, 0, len(b.breakers))
+	for _, breaker := range b.breakers {
+		breakers = append(breakers, breaker)
+	}
+	b.mu.Unlock()
+
+	status := make([]CircuitStatus, len(breakers))
+	for i, breaker := range breakers {
+		status[i] = breaker.status()
+	}
+	sort.Slice(status, func(i, j int) bool {
+		return status[i].Key < status[j].Key
+	})
+	return status
+}
+
+// circuitBreaker is the breaker of one connection or host
+type circuitBreaker struct {
+	key    string
+	config CircuitBreakerConfig
+	now    func() time.Time
+
+	mu        sync.Mutex
+	state     string
+	failures  int
+	openedAt  time.Time
+	probes    int // Probes in flight while half-open
+	successes int // Probes succeeded while half-open
+}
+
+func newCircuitBreaker(key string, config CircuitBreakerConfig, now func() time.Time) *circuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultFailureThreshold
+	}
+	if config.OpenDuration <= 0 {
+		config.OpenDuration = DefaultOpenDuration
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = DefaultHalfOpenProbes
+	}
+	return &circuitBreaker{key: key, config: config, now: now, state: CircuitClosed}
+}
+
+// allow reports whether a request may be sent. Requests that are allowed must
+// report their outcome with done.
+func (c *circuitBreaker) allow() (done func(success bool), err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitOpen && !c.now().Before(c.openedAt.Add(c.config.OpenDuration)) {
+		c.state, c.probes, c.successes = CircuitHalfOpen, 0, 0
+	}
+
+	switch c.state {
+	case CircuitOpen:
+		return nil, c.openError()
+	case CircuitHalfOpen:
+		if c.probes+c.successes >= c.config.HalfOpenProbes {
+			return nil, c.openError()
+		}
+		c.probes++
+	}
+	return c.record, nil
+}
+
+// record updates the breaker with the outcome of a request
+func (c *circuitBreaker) record(success bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch c.state {
+	case CircuitHalfOpen:
+		c.probes--
+		if !success {
+			c.open()
+			return
+		}
+		c.successes++
+		if c.successes >= c.config.HalfOpenProbes {
+			c.state, c.failures = CircuitClosed, 0
+		}
+	case CircuitClosed:
+		if success {
+			c.failures = 0
+			return
+		}
+		c.failures++
+		if c.failures >= c.config.FailureThreshold {
+			c.open()
+		}
+	}
+}
+
+// release frees the slot of an allowed request that ended without an outcome,
+// such as one cancelled by its caller
+func (c *circuitBreaker) release() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.state == CircuitHalfOpen && c.probes > 0 {
+		c.probes--
+	}
+}
+
+// open opens the breaker. The caller must hold c.mu.
+func (c *circuitBreaker) open() {
+	c.state = CircuitOpen
+	c.openedAt = c.now()
+	c.failures = c.config.FailureThreshold
+}
+
+// openError returns the error of a request rejected by the breaker. Retrying
+// it before the breaker closes would fail the same way.
+func (c *circuitBreaker) openError() error {
+	return retry.Permanent(fmt.Errorf("%w: %s (retry after %s)", ErrCircuitOpen, c.key,
+		c.openedAt.Add(c.config.OpenDuration).Format(time.RFC3339)))
+}
+
+func (c *circuitBreaker) status() CircuitStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	status := CircuitStatus{Key: c.key, State: c.state, Failures: c.failures}
+	if !c.openedAt.IsZero() {
+		openedAt, retryAt := c.openedAt, c.openedAt.Add(c.config.OpenDuration)
+		status.OpenedAt = &openedAt
+		if c.state == CircuitOpen {
+			status.RetryAt = &retryAt
+		}
+	}
+
+	// O breaker só passa a meio aberto na próxima requisição, mas já aceita sondas
+	if c.state == CircuitOpen && !c.now().Before(*status.RetryAt) {
+		status.State, status.RetryAt = CircuitHalfOpen, nil
+	}
+	return status
+}
+
+// circuitFailure reports whether the outcome of a request counts as a failure
+// of the API for its circuit breaker
+func circuitFailure(resp *Response, err error) bool {
+	if err != nil {
+		return retry.Classify(err) != ""
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
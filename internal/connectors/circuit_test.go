@@ -0,0 +1,208 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var requests, failing atomic.Int32
+	failing.Store(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breakers := NewCircuitBreakers()
+	breakers.now = func() time.Time { return now }
+
+	connector := NewRestConnector(Config{
+		BaseURL:        server.URL,
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, OpenDuration: time.Minute},
+	})
+	connector.UseCircuitBreakers(breakers, "payments")
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	execute := func() (*Response, error) {
+		return connector.Execute(context.Background(), Request{Method: "GET", URL: "/status"})
+	}
+
+	// Duas falhas consecutivas abrem o circuito
+	for i := 0; i < 2; i++ {
+		if resp, err := execute(); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("Esperava a resposta 503: %v %v", resp, err)
+		}
+	}
+	status := breakers.Status()
+	if len(status) != 1 || status[0].Key != "payments" || status[0].State != CircuitOpen || status[0].RetryAt == nil {
+		t.Fatalf("Esperava o circuito aberto: %+v", status)
+	}
+
+	// Com o circuito aberto a API não é chamada
+	_, err := execute()
+	if !errors.Is(err, ErrCircuitOpen) || !strings.Contains(err.Error(), "payments") {
+		t.Fatalf("Esperava ErrCircuitOpen, obteve %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Esperava 2 requisições, obteve %d", requests.Load())
+	}
+
+	// Após open_duration uma sonda que falha reabre o circuito
+	now = now.Add(time.Minute)
+	if state := breakers.Status()[0].State; state != CircuitHalfOpen {
+		t.Errorf("Esperava o circuito meio aberto, obteve %s", state)
+	}
+	if resp, err := execute(); err != nil || resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("Esperava a sonda com resposta 503: %v %v", resp, err)
+	}
+	if _, err := execute(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Esperava o circuito reaberto, obteve %v", err)
+	}
+
+	// Uma sonda bem-sucedida fecha o circuito
+	now = now.Add(time.Minute)
+	failing.Store(0)
+	if resp, err := execute(); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Esperava a sonda com sucesso: %v %v", resp, err)
+	}
+	if status := breakers.Status()[0]; status.State != CircuitClosed || status.Failures != 0 {
+		t.Errorf("Esperava o circuito fechado: %+v", status)
+	}
+	if requests.Load() != 4 {
+		t.Errorf("Esperava 4 requisições, obteve %d", requests.Load())
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker("api", CircuitBreakerConfig{FailureThreshold: 1, HalfOpenProbes: 2}, func() time.Time { return now })
+
+	done, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("Esperava o circuito fechado: %v", err)
+	}
+	done(false)
+
+	// Só half_open_probes sondas passam ao mesmo tempo
+	now = now.Add(DefaultOpenDuration)
+	first, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("Esperava a primeira sonda: %v", err)
+	}
+	second, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("Esperava a segunda sonda: %v", err)
+	}
+	if _, err := breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Esperava a terceira requisição rejeitada, obteve %v", err)
+	}
+
+	first(true)
+	if breaker.status().State != CircuitHalfOpen {
+		t.Errorf("O circuito só deve fechar após todas as sondas")
+	}
+	second(true)
+	if breaker.status().State != CircuitClosed {
+		t.Errorf("Esperava o circuito fechado, obteve %s", breaker.status().State)
+	}
+}
+
+func TestCircuitBreakerIgnoresCancellation(t *testing.T) {
+	var failing atomic.Int32
+	failing.Store(1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	breakers := NewCircuitBreakers()
+	connector := NewRestConnector(Config{
+		BaseURL:        server.URL,
+		CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2},
+	})
+	connector.UseCircuitBreakers(breakers, "api")
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+
+	connector.Execute(context.Background(), Request{Method: "GET", URL: "/"})
+
+	// Requisições canceladas por quem chama não contam como sucesso nem como falha
+	failing.Store(0)
+	expired, cancelExpired := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelExpired()
+	cancelled, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	for _, ctx := range []context.Context{expired, cancelled} {
+		if _, err := connector.Execute(ctx, Request{Method: "GET", URL: "/"}); err == nil {
+			t.Fatal("Esperava o erro de cancelamento")
+		}
+	}
+	if status := breakers.Status()[0]; status.State != CircuitClosed || status.Failures != 1 {
+		t.Errorf("Esperava a falha anterior preservada: %+v", status)
+	}
+}
+
+func TestCircuitBreakerHalfOpenRelease(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	breaker := newCircuitBreaker("api", CircuitBreakerConfig{FailureThreshold: 1}, func() time.Time { return now })
+
+	done, _ := breaker.allow()
+	done(false)
+
+	// Uma sonda cancelada libera a vaga para a próxima
+	now = now.Add(DefaultOpenDuration)
+	if _, err := breaker.allow(); err != nil {
+		t.Fatalf("Esperava a sonda: %v", err)
+	}
+	breaker.release()
+	done, err := breaker.allow()
+	if err != nil {
+		t.Fatalf("Esperava uma nova sonda após a liberação: %v", err)
+	}
+	done(true)
+	if breaker.status().State != CircuitClosed {
+		t.Errorf("Esperava o circuito fechado, obteve %s", breaker.status().State)
+	}
+}
+
+func TestCircuitBreakerScopeHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	breakers := NewCircuitBreakers()
+	for _, name := range []string{"a", "b"} {
+		connector := NewRestConnector(Config{
+			BaseURL:        server.URL,
+			CircuitBreaker: &CircuitBreakerConfig{FailureThreshold: 2, Scope: "host"},
+		})
+		connector.UseCircuitBreakers(breakers, name)
+		connector.Connect(context.Background())
+		connector.Execute(context.Background(), Request{Method: "GET", URL: "/"})
+	}
+
+	// As duas conexões compartilham o circuito do host
+	status := breakers.Status()
+	if len(status) != 1 || status[0].Key != strings.TrimPrefix(server.URL, "http://") || status[0].State != CircuitOpen {
+		t.Errorf("Esperava um circuito aberto por host: %+v", status)
+	}
+}
@@ -6,7 +6,11 @@ package connectors
 
 import (
 	"context"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/carloskvasir/goflow/internal/retry"
 )
 
 // Request represents a generic request for any type of API
@@ -25,38 +29,118 @@ type Response struct {
 	Headers    map[string]string
 	Body       []byte
 	Error      error
+	Attempts   []retry.Attempt // Outcome of every attempt made to get the response
+
+	RateLimitWait time.Duration // Time spent waiting for the rate limit
 }
 
-// RetryConfig configures the retry policy for requests
+// RetryConfig configures the retry policy for requests. Transport errors,
+// timeouts and the status codes in RetryOnStatus (default
+// retry.DefaultRetryableStatus) are retried.
 type RetryConfig struct {
-	MaxRetries  int
-	RetryDelay  time.Duration
-	MaxDelay    time.Duration
-	Multiplier  float64
+	MaxRetries    int           `json:"max_retries"`
+	RetryDelay    time.Duration `json:"retry_delay"`
+	MaxDelay      time.Duration `json:"max_delay"`
+	Multiplier    float64       `json:"multiplier"`
+	Jitter        float64       `json:"jitter,omitempty"`          // Fraction of each delay randomized, between 0 and 1
+	RetryOnStatus []int         `json:"retry_on_status,omitempty"` // Status codes retried
 }
 
-// Config represents the base configuration for any connector
+// Policy returns the retry policy described by the config
+func (c RetryConfig) Policy() retry.Policy {
+	return retry.Policy{
+		MaxAttempts:     c.MaxRetries + 1,
+		Delay:           c.RetryDelay,
+		MaxDelay:        c.MaxDelay,
+		Multiplier:      c.Multiplier,
+		Jitter:          c.Jitter,
+		RetryableStatus: c.RetryOnStatus,
+	}
+}
+
+// Config represents the base configuration for any connector. Named
+// connections are declared with this configuration, either per workflow or
+// globally.
 type Config struct {
-	BaseURL     string
-	Timeout     time.Duration
-	RetryConfig RetryConfig
-	Auth        AuthConfig
+	BaseURL     string            `json:"base_url,omitempty"`
+	Timeout     time.Duration     `json:"timeout,omitempty"`
+	RetryConfig RetryConfig       `json:"retry,omitempty"`
+	Auth        AuthConfig        `json:"auth,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"` // Default headers of every request
+	TLS         *TLSConfig        `json:"tls,omitempty"`
+	Proxy       string            `json:"proxy,omitempty"` // URL of the HTTP proxy (default taken from the environment)
+
+	CircuitBreaker *CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
+	RateLimit      *RateLimitConfig      `json:"rate_limit,omitempty"`
+}
+
+// Redacted is the value shown in place of secrets
+const Redacted = "[redacted]"
+
+// Redacted returns a copy of the config safe to expose, e.g. in API responses:
+// credentials, sensitive headers and inline TLS keys are replaced by Redacted,
+// and the proxy password by "xxxxx".
+func (c Config) Redacted() Config {
+	if len(c.Headers) > 0 {
+		headers := make(map[string]string, len(c.Headers))
+		for name, value := range c.Headers {
+			if sensitiveHeader(name) {
+				value = Redacted
+			}
+			headers[name] = value
+		}
+		c.Headers = headers
+	}
+
+	if len(c.Auth.Credentials) > 0 {
+		credentials := make(map[string]string, len(c.Auth.Credentials))
+		for key := range c.Auth.Credentials {
+			credentials[key] = Redacted
+		}
+		c.Auth.Credentials = credentials
+	}
+
+	if c.TLS != nil && c.TLS.Key != "" {
+		tlsConfig := *c.TLS
+		tlsConfig.Key = Redacted
+		c.TLS = &tlsConfig
+	}
+
+	if proxyURL, err := url.Parse(c.Proxy); err == nil {
+		c.Proxy = proxyURL.Redacted()
+	}
+	return c
+}
+
+// sensitiveHeaders lists the headers that carry credentials. Headers whose
+// names contain "token", "secret" or "key" are also treated as sensitive.
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// sensitiveHeader reports whether the value of a header must be redacted
+func sensitiveHeader(name string) bool {
+	for _, sensitive := range sensitiveHeaders {
+		if strings.EqualFold(name, sensitive) {
+			return true
+		}
+	}
+	name = strings.ToLower(name)
+	return strings.Contains(name, "token") || strings.Contains(name, "secret") || strings.Contains(name, "key")
 }
 
 // AuthConfig represents the authentication configuration
 type AuthConfig struct {
-	Type        string // "basic", "oauth2", "apikey"
-	Credentials map[string]string
+	Type        string            `json:"type,omitempty"` // "basic", "bearer", "apikey", "oauth2", "hmac", "aws_sigv4"
+	Credentials map[string]string `json:"credentials,omitempty"`
 }
 
 // Connector is the base interface for all API connector types
 type Connector interface {
 	// Connect establishes the initial connection with the API
 	Connect(ctx context.Context) error
-	
+
 	// Execute performs a request to the API
 	Execute(ctx context.Context, req Request) (*Response, error)
-	
+
 	// Close closes the connection and releases resources
 	Close() error
 }
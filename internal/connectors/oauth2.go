@@ -0,0 +1,160 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package connectors
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TokenExpiryLeeway is how long before its expiry an OAuth2 token is refreshed
+const TokenExpiryLeeway = 30 * time.Second
+
+// tokenSource fetches OAuth2 access tokens with the client credentials grant
+// and caches them until they are about to expire. It is safe for concurrent
+// use, so every request of a connector shares the same token.
+type tokenSource struct {
+	client       *http.Client
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scope        string
+	audience     string
+	authStyle    string // "header" (default) sends the client credentials as Basic auth, "params" in the form
+	now          func() time.Time
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// newTokenSource creates a token source from the credentials of an oauth2
+// auth config: token_url, client_id and client_secret, plus the optional
+// scope, audience and auth_style.
+func newTokenSource(client *http.Client, credentials map[string]string) (*tokenSource, error) {
+	var missing []string
+	for _, key := range []string{"token_url", "client_id", "client_secret"} {
+		if credentials[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("oauth2 requires credentials: %s", strings.Join(missing, ", "))
+	}
+
+	authStyle := credentials["auth_style"]
+	if authStyle != "" && authStyle != "header" && authStyle != "params" {
+		return nil, fmt.Errorf("unknown oauth2 auth_style: %s", authStyle)
+	}
+
+	return &tokenSource{
+		client:       client,
+		tokenURL:     credentials["token_url"],
+		clientID:     credentials["client_id"],
+		clientSecret: credentials["client_secret"],
+		scope:        credentials["scope"],
+		audience:     credentials["audience"],
+		authStyle:    authStyle,
+		now:          time.Now,
+	}, nil
+}
+
+// Token returns the cached access token, fetching a new one if there is none
+// or if it expires within TokenExpiryLeeway.
+func (t *tokenSource) Token(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && (t.expiry.IsZero() || t.now().Add(TokenExpiryLeeway).Before(t.expiry)) {
+		return t.token, nil
+	}
+
+	token, err := t.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	t.token = token.AccessToken
+	t.expiry = time.Time{}
+	if token.ExpiresIn > 0 {
+		t.expiry = t.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return t.token, nil
+}
+
+// Invalidate drops the cached token if it is still the given one, so that the
+// next call to Token fetches a new one. Requests that got a 401 with a token
+// refreshed meanwhile by another request do not discard the new token.
+func (t *tokenSource) Invalidate(token string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token == token {
+		t.token = ""
+		t.expiry = time.Time{}
+	}
+}
+
+// fetch requests a new token from the token endpoint
+func (t *tokenSource) fetch(ctx context.Context) (*tokenResponse, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if t.scope != "" {
+		form.Set("scope", t.scope)
+	}
+	if t.audience != "" {
+		form.Set("audience", t.audience)
+	}
+	if t.authStyle == "params" {
+		form.Set("client_id", t.clientID)
+		form.Set("client_secret", t.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if t.authStyle != "params" {
+		req.SetBasicAuth(url.QueryEscape(t.clientID), url.QueryEscape(t.clientSecret))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading token response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("error decoding token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	return &token, nil
+}
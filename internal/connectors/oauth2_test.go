@@ -0,0 +1,197 @@
+package connectors
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tokenServer emite tokens numerados e aceita apenas o token mais recente
+type tokenServer struct {
+	mu      sync.Mutex
+	issued  int
+	current string
+	forms   []string
+}
+
+func (s *tokenServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "client" || pass != "s3cret" {
+			http.Error(w, `{"error": "invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		r.ParseForm()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.issued++
+		s.current = fmt.Sprintf("token-%d", s.issued)
+		s.forms = append(s.forms, r.Form.Encode())
+		fmt.Fprintf(w, `{"access_token": %q, "token_type": "Bearer", "expires_in": 3600}`, s.current)
+	})
+	mux.HandleFunc("/data", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if r.Header.Get("Authorization") != "Bearer "+s.current {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"ok": true}`))
+	})
+	return mux
+}
+
+// revoke invalida o token atual sem emitir outro
+func (s *tokenServer) revoke() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.current = "revoked"
+}
+
+func (s *tokenServer) issuedTokens() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issued
+}
+
+func newOAuth2Connector(t *testing.T, serverURL string) *RestConnector {
+	connector := NewRestConnector(Config{
+		BaseURL: serverURL,
+		Auth: AuthConfig{
+			Type: "oauth2",
+			Credentials: map[string]string{
+				"token_url":     serverURL + "/token",
+				"client_id":     "client",
+				"client_secret": "s3cret",
+				"scope":         "read write",
+			},
+		},
+	})
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	return connector
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens.handler())
+	defer server.Close()
+
+	connector := newOAuth2Connector(t, server.URL)
+	defer connector.Close()
+
+	// O token é obtido uma única vez e compartilhado entre as requisições
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := connector.Execute(context.Background(), Request{Method: "GET", URL: "/data"})
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Errorf("Requisição falhou: %v %v", resp, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if issued := tokens.issuedTokens(); issued != 1 {
+		t.Errorf("Esperava 1 token emitido, mas obteve %d", issued)
+	}
+	if tokens.forms[0] != "grant_type=client_credentials&scope=read+write" {
+		t.Errorf("Formulário inesperado: %s", tokens.forms[0])
+	}
+
+	// Um token revogado gera 401: o conector renova o token e repete a requisição
+	tokens.revoke()
+	resp, err := connector.Execute(context.Background(), Request{Method: "GET", URL: "/data"})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Esperava sucesso após renovar o token: %v %v", resp, err)
+	}
+	if issued := tokens.issuedTokens(); issued != 2 {
+		t.Errorf("Esperava 2 tokens emitidos, mas obteve %d", issued)
+	}
+}
+
+func TestOAuth2TokenRefreshBeforeExpiry(t *testing.T) {
+	tokens := &tokenServer{}
+	server := httptest.NewServer(tokens.handler())
+	defer server.Close()
+
+	connector := newOAuth2Connector(t, server.URL)
+	defer connector.Close()
+
+	now := time.Now()
+	connector.tokens.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := connector.Execute(context.Background(), Request{Method: "GET", URL: "/data"}); err != nil {
+			t.Fatalf("Erro na requisição: %v", err)
+		}
+	}
+	if issued := tokens.issuedTokens(); issued != 1 {
+		t.Errorf("Esperava 1 token emitido, mas obteve %d", issued)
+	}
+
+	// Perto da expiração o token é renovado antes da requisição
+	now = now.Add(time.Hour - TokenExpiryLeeway)
+	resp, err := connector.Execute(context.Background(), Request{Method: "GET", URL: "/data"})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Requisição falhou: %v %v", resp, err)
+	}
+	if issued := tokens.issuedTokens(); issued != 2 {
+		t.Errorf("Esperava 2 tokens emitidos, mas obteve %d", issued)
+	}
+}
+
+func TestOAuth2InvalidClient(t *testing.T) {
+	server := httptest.NewServer((&tokenServer{}).handler())
+	defer server.Close()
+
+	connector := NewRestConnector(Config{
+		Auth: AuthConfig{
+			Type:        "oauth2",
+			Credentials: map[string]string{"token_url": server.URL + "/token", "client_id": "client", "client_secret": "wrong"},
+		},
+	})
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+
+	if _, err := connector.Execute(context.Background(), Request{Method: "GET", URL: server.URL + "/data"}); err == nil {
+		t.Error("Esperava erro com credenciais inválidas")
+	}
+
+	missing := NewRestConnector(Config{Auth: AuthConfig{Type: "oauth2", Credentials: map[string]string{"client_id": "client"}}})
+	if err := missing.Connect(context.Background()); err == nil {
+		t.Error("Esperava erro sem token_url e client_secret")
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	connector := NewRestConnector(Config{
+		BaseURL: server.URL,
+		Auth:    AuthConfig{Type: "basic", Credentials: map[string]string{"username": "ana", "password": "p@ss:word"}},
+	})
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+
+	resp, err := connector.Execute(context.Background(), Request{Method: "GET", URL: "/"})
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+	if string(resp.Body) != "Basic YW5hOnBAc3M6d29yZA==" {
+		t.Errorf("Header Authorization inesperado: %s", resp.Body)
+	}
+}
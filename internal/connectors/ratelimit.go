@@ -0,0 +1,164 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package connectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/retry"
+)
+
+// ErrRateLimited is returned, without calling the API, for requests that would
+// exceed the rate limit of their connection or host
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// Rate limit policies
+const (
+	RateLimitWait = "wait" // Requests wait for their turn
+	RateLimitFail = "fail" // Requests over the limit fail with ErrRateLimited
+)
+
+// RateLimitConfig configures the rate limit of a connection as a token bucket
+// that refills at Rate requests per second and holds up to Burst requests.
+type RateLimitConfig struct {
+	Rate    float64       `json:"rate"`               // Requests per second
+	Burst   int           `json:"burst,omitempty"`    // Requests allowed at once (default Rate rounded up)
+	Scope   string        `json:"scope,omitempty"`    // "connection" (default) or "host"
+	Policy  string        `json:"policy,omitempty"`   // RateLimitWait (default) or RateLimitFail
+	MaxWait time.Duration `json:"max_wait,omitempty"` // Longest wait before failing with ErrRateLimited (0 means unlimited)
+}
+
+// validate checks the rate limit settings
+func (c *RateLimitConfig) validate() error {
+	if c.Rate <= 0 {
+		return fmt.Errorf("rate limit requires a positive rate")
+	}
+	if c.Burst < 0 || c.MaxWait < 0 {
+		return fmt.Errorf("rate limit burst and max_wait cannot be negative")
+	}
+	switch c.Policy {
+	case "", RateLimitWait, RateLimitFail:
+		return nil
+	default:
+		return fmt.Errorf("unknown rate limit policy: %s", c.Policy)
+	}
+}
+
+// RateLimiters holds the rate limiters of a set of connectors, keyed by
+// connection name or host, so that every connector calling the same API shares
+// the same quota.
+type RateLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+	now      func() time.Time
+}
+
+// NewRateLimiters creates an empty set of rate limiters
+func NewRateLimiters() *RateLimiters {
+	return &RateLimiters{
+		limiters: make(map[string]*rateLimiter),
+		now:      time.Now,
+	}
+}
+
+// limiter returns the limiter of a key, creating it with the given config
+func (l *RateLimiters) limiter(key string, config RateLimitConfig) *rateLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	limiter, exists := l.limiters[key]
+	if !exists {
+		limiter = newRateLimiter(key, config, l.now)
+		l.limiters[key] = limiter
+	}
+	return limiter
+}
+
+// Reset drops the limiters whose key starts with prefix, so that the next
+// request builds them again from the current config of its connection
+func (l *RateLimiters) Reset(prefix string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key := range l.limiters {
+		if strings.HasPrefix(key, prefix) {
+			delete(l.limiters, key)
+		}
+	}
+}
+
+// rateLimiter is the token bucket of one connection or host
+type rateLimiter struct {
+	key    string
+	config RateLimitConfig
+	now    func() time.Time
+
+	mu     sync.Mutex
+	tokens float64 // Negative while requests wait for tokens they reserved
+	last   time.Time
+}
+
+func newRateLimiter(key string, config RateLimitConfig, now func() time.Time) *rateLimiter {
+	if config.Burst <= 0 {
+		config.Burst = int(math.Max(1, math.Ceil(config.Rate)))
+	}
+	return &rateLimiter{key: key, config: config, now: now, tokens: float64(config.Burst), last: now()}
+}
+
+// wait takes a token from the bucket, waiting for it under the wait policy,
+// and returns how long it waited
+func (l *rateLimiter) wait(ctx context.Context) (time.Duration, error) {
+	wait, err := l.reserve()
+	if err != nil {
+		return 0, err
+	}
+	if wait <= 0 {
+		return 0, nil
+	}
+
+	// O token reservado volta ao bucket se a espera for interrompida
+	if err := retry.Sleep(ctx, wait); err != nil {
+		l.release()
+		return 0, err
+	}
+	return wait, nil
+}
+
+// reserve takes a token from the bucket and returns how long to wait before
+// using it. Under the fail policy, or if the wait would be longer than MaxWait,
+// it returns ErrRateLimited without taking the token.
+func (l *rateLimiter) reserve() (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens = math.Min(float64(l.config.Burst), l.tokens+now.Sub(l.last).Seconds()*l.config.Rate)
+	l.last = now
+
+	var wait time.Duration
+	if l.tokens < 1 {
+		wait = time.Duration((1 - l.tokens) / l.config.Rate * float64(time.Second))
+		if l.config.Policy == RateLimitFail || (l.config.MaxWait > 0 && wait > l.config.MaxWait) {
+			return 0, retry.Permanent(fmt.Errorf("%w: %s (next request in %s)", ErrRateLimited, l.key,
+				wait.Round(time.Millisecond)))
+		}
+	}
+	l.tokens--
+	return wait, nil
+}
+
+// release returns a reserved token to the bucket
+func (l *rateLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = math.Min(float64(l.config.Burst), l.tokens+1)
+}
@@ -0,0 +1,110 @@
+package connectors
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	limiter := newRateLimiter("api", RateLimitConfig{Rate: 2, Burst: 2}, clock)
+	for i := 0; i < 2; i++ {
+		if wait, err := limiter.reserve(); err != nil || wait != 0 {
+			t.Fatalf("Esperava a requisição %d sem espera: %v %v", i+1, wait, err)
+		}
+	}
+
+	// Com o bucket vazio as requisições esperam na fila
+	if wait, _ := limiter.reserve(); wait != 500*time.Millisecond {
+		t.Errorf("Esperava 500ms de espera, obteve %v", wait)
+	}
+	if wait, _ := limiter.reserve(); wait != time.Second {
+		t.Errorf("Esperava 1s de espera, obteve %v", wait)
+	}
+
+	// O bucket é reabastecido com o tempo, até o burst
+	now = now.Add(time.Hour)
+	if wait, err := limiter.reserve(); err != nil || wait != 0 {
+		t.Errorf("Esperava o bucket cheio: %v %v", wait, err)
+	}
+
+	// Com a política fail a requisição falha sem consumir o token
+	failing := newRateLimiter("api", RateLimitConfig{Rate: 1, Policy: RateLimitFail}, clock)
+	failing.reserve()
+	if _, err := failing.reserve(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Esperava ErrRateLimited, obteve %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := failing.reserve(); err != nil {
+		t.Errorf("Esperava o token reabastecido: %v", err)
+	}
+
+	// max_wait limita a espera da política wait
+	bounded := newRateLimiter("api", RateLimitConfig{Rate: 1, MaxWait: 500 * time.Millisecond}, clock)
+	bounded.reserve()
+	if _, err := bounded.reserve(); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Esperava ErrRateLimited acima de max_wait, obteve %v", err)
+	}
+}
+
+func TestRestConnectorRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	// Dois conectores da mesma conexão compartilham a cota
+	limiters := NewRateLimiters()
+	var connectors []*RestConnector
+	for i := 0; i < 2; i++ {
+		connector := NewRestConnector(Config{BaseURL: server.URL, RateLimit: &RateLimitConfig{Rate: 20, Burst: 1}})
+		connector.UseRateLimiters(limiters, "partner")
+		if err := connector.Connect(context.Background()); err != nil {
+			t.Fatalf("Erro ao conectar: %v", err)
+		}
+		connectors = append(connectors, connector)
+	}
+
+	start := time.Now()
+	var waited time.Duration
+	for i := 0; i < 3; i++ {
+		resp, err := connectors[i%2].Execute(context.Background(), Request{Method: "GET", URL: "/"})
+		if err != nil {
+			t.Fatalf("Erro inesperado: %v", err)
+		}
+		waited += resp.RateLimitWait
+	}
+
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Esperava as requisições espaçadas em 50ms, levaram %v", elapsed)
+	}
+	if waited < 90*time.Millisecond {
+		t.Errorf("Esperava a espera registrada na resposta, obteve %v", waited)
+	}
+
+	// O cancelamento interrompe a espera e devolve o token
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	limiter := limiters.limiter("partner", RateLimitConfig{})
+	if _, err := limiter.wait(ctx); err == nil {
+		t.Fatal("Esperava o cancelamento da espera")
+	}
+	if _, err := connectors[0].Execute(context.Background(), Request{Method: "GET", URL: "/"}); err != nil {
+		t.Errorf("Erro inesperado após o cancelamento: %v", err)
+	}
+}
+
+func TestRateLimitConfigValidation(t *testing.T) {
+	for _, config := range []RateLimitConfig{{}, {Rate: 1, Burst: -1}, {Rate: 1, Policy: "drop"}} {
+		connector := NewRestConnector(Config{RateLimit: &config})
+		if err := connector.Connect(context.Background()); err == nil {
+			t.Errorf("Esperava erro para %+v", config)
+		}
+	}
+}
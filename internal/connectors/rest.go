@@ -7,18 +7,35 @@ package connectors
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/carloskvasir/goflow/internal/retry"
 )
 
+// DefaultTimeout bounds each attempt of a request when neither the config nor
+// the context of the request sets a timeout
+const DefaultTimeout = 30 * time.Second
+
 // RestConnector implements the Connector interface for REST APIs
 type RestConnector struct {
 	client  *http.Client
 	config  Config
 	headers map[string]string
+	tokens  *tokenSource  // Set when the connector uses OAuth2
+	signer  requestSigner // Set when the connector signs its requests
+
+	// breakers and limiters hold the circuit breaker and the rate limiter of
+	// the connection, keyed by name
+	breakers *CircuitBreakers
+	limiters *RateLimiters
+	name     string
 }
 
 // NewRestConnector creates a new instance of RestConnector
@@ -28,72 +45,170 @@ func NewRestConnector(config Config) *RestConnector {
 	}
 
 	return &RestConnector{
-		client:  client,
-		config:  config,
-		headers: make(map[string]string),
+		client:   client,
+		config:   config,
+		headers:  make(map[string]string),
+		breakers: NewCircuitBreakers(),
+		limiters: NewRateLimiters(),
 	}
 }
 
+// UseCircuitBreakers makes the connector share the circuit breakers of other
+// connectors, its own being the one of the named connection. Connectors
+// without a name, or whose breaker is scoped by host, use the breaker of the
+// host of each request.
+func (r *RestConnector) UseCircuitBreakers(breakers *CircuitBreakers, name string) {
+	r.breakers = breakers
+	r.name = name
+}
+
+// UseRateLimiters makes the connector share the rate limiters of other
+// connectors, its own being the one of the named connection. Connectors
+// without a name, or whose limit is scoped by host, use the limiter of the
+// host of each request.
+func (r *RestConnector) UseRateLimiters(limiters *RateLimiters, name string) {
+	r.limiters = limiters
+	r.name = name
+}
+
 // Connect implements the Connect method of the Connector interface
 func (r *RestConnector) Connect(ctx context.Context) error {
 	// Set default headers
 	r.headers["Content-Type"] = "application/json"
-	
+	for k, v := range r.config.Headers {
+		r.headers[k] = v
+	}
+
+	// Configure TLS and proxy
+	transport, err := newTransport(r.config)
+	if err != nil {
+		return fmt.Errorf("failed to setup transport: %w", err)
+	}
+	r.client.Transport = transport
+
+	if r.config.RateLimit != nil {
+		if err := r.config.RateLimit.validate(); err != nil {
+			return err
+		}
+	}
+
 	// Configure authentication
 	if err := r.setupAuth(); err != nil {
 		return fmt.Errorf("failed to setup authentication: %w", err)
 	}
-	
+
 	return nil
 }
 
 // Execute implements the Execute method of the Connector interface
 func (r *RestConnector) Execute(ctx context.Context, req Request) (*Response, error) {
-	url := r.buildURL(req.URL)
-	
+	if req.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+		defer cancel()
+	}
+
+	fullURL := r.buildURL(req.URL)
+
 	// Prepare request body
-	var bodyReader io.Reader
+	var jsonBody []byte
 	if req.Body != nil {
-		jsonBody, err := json.Marshal(req.Body)
+		var err error
+		jsonBody, err = json.Marshal(req.Body)
 		if err != nil {
 			return nil, fmt.Errorf("error serializing body: %w", err)
 		}
-		bodyReader = bytes.NewReader(jsonBody)
-	}
-	
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, req.Method, url, bodyReader)
-	if err != nil {
-		return nil, fmt.Errorf("error creating request: %w", err)
 	}
-	
-	// Add headers
-	for k, v := range r.headers {
-		httpReq.Header.Set(k, v)
-	}
-	for k, v := range req.Headers {
-		httpReq.Header.Set(k, v)
+
+	// Cada tentativa recria a requisição, e com ela o corpo, a autenticação e a assinatura
+	policy := r.retryPolicy(req)
+	var response *Response
+	var waited time.Duration
+	attempts, err := retry.Do(ctx, policy, func(ctx context.Context, attempt int) error {
+		response = nil
+
+		// Cada tentativa consome a cota da conexão
+		if r.config.RateLimit != nil {
+			limiter := r.limiters.limiter(r.scopeKey(r.config.RateLimit.Scope, fullURL), *r.config.RateLimit)
+			wait, err := limiter.wait(ctx)
+			waited += wait
+			if err != nil {
+				return err
+			}
+		}
+
+		// Com o circuito aberto a requisição falha sem chamar a API
+		var breaker *circuitBreaker
+		var done func(success bool)
+		if r.config.CircuitBreaker != nil {
+			var err error
+			breaker = r.breakers.breaker(r.scopeKey(r.config.CircuitBreaker.Scope, fullURL), *r.config.CircuitBreaker)
+			if done, err = breaker.allow(); err != nil {
+				return err
+			}
+		}
+
+		// Sem timeout configurado, só o prazo de quem chama limita a requisição
+		requestCtx := ctx
+		if r.config.Timeout == 0 {
+			if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+				var cancel context.CancelFunc
+				requestCtx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+				defer cancel()
+			}
+		}
+
+		resp, err := r.do(requestCtx, req, fullURL, jsonBody)
+		switch {
+		case breaker == nil:
+		case err != nil && ctx.Err() != nil:
+			// O cancelamento ou o prazo de quem chama não dizem nada sobre a saúde da API
+			breaker.release()
+		default:
+			done(!circuitFailure(resp, err))
+		}
+		if err != nil {
+			return err
+		}
+		response = resp
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return retry.NewStatusError(resp.StatusCode, resp.Body, resp.Headers["Retry-After"])
+		}
+		return nil
+	})
+
+	// Respostas sem sucesso não são erros do conector: quem chama decide o que fazer
+	if response != nil {
+		response.Attempts = attempts
+		response.RateLimitWait = waited
+		return response, nil
 	}
-	
-	// Execute request with retry if configured
-	var resp *http.Response
-	if req.RetryConfig != nil {
-		resp, err = r.executeWithRetry(httpReq, *req.RetryConfig)
-	} else {
-		resp, err = r.client.Do(httpReq)
+	return nil, fmt.Errorf("error executing request: %w", err)
+}
+
+// do executes one attempt of a request and reads its response. An OAuth2
+// token rejected with a 401 is refreshed and the request sent once more.
+func (r *RestConnector) do(ctx context.Context, req Request, fullURL string, jsonBody []byte) (*Response, error) {
+	resp, token, err := r.send(ctx, req, fullURL, jsonBody)
+
+	// Um 401 com token OAuth2 pode indicar token revogado: renova e tenta uma vez mais
+	if err == nil && resp.StatusCode == http.StatusUnauthorized && r.tokens != nil {
+		resp.Body.Close()
+		r.tokens.Invalidate(token)
+		resp, _, err = r.send(ctx, req, fullURL, jsonBody)
 	}
-	
+
 	if err != nil {
-		return nil, fmt.Errorf("error executing request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
-	
+
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
-	
+
 	// Convert headers
 	headers := make(map[string]string)
 	for k, v := range resp.Header {
@@ -101,14 +216,78 @@ func (r *RestConnector) Execute(ctx context.Context, req Request) (*Response, er
 			headers[k] = v[0]
 		}
 	}
-	
+
 	return &Response{
 		StatusCode: resp.StatusCode,
 		Headers:    headers,
-		Body:      body,
+		Body:       body,
 	}, nil
 }
 
+// scopeKey returns the key of the circuit breaker or rate limiter of a
+// request: the connection name or, for the "host" scope, the request host
+func (r *RestConnector) scopeKey(scope, fullURL string) string {
+	if r.name != "" && scope != "host" {
+		return r.name
+	}
+	if u, err := url.Parse(fullURL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return fullURL
+}
+
+// retryPolicy returns the retry policy of a request: its own retry config or,
+// if it has none, the retry config of the connector
+func (r *RestConnector) retryPolicy(req Request) retry.Policy {
+	config := r.config.RetryConfig
+	if req.RetryConfig != nil {
+		config = *req.RetryConfig
+	}
+	return config.Policy()
+}
+
+// send creates and executes one HTTP request, returning the OAuth2 token it was
+// authorized with, if any
+func (r *RestConnector) send(ctx context.Context, req Request, fullURL string, jsonBody []byte) (*http.Response, string, error) {
+	var bodyReader io.Reader
+	if jsonBody != nil {
+		bodyReader = bytes.NewReader(jsonBody)
+	}
+
+	// Create HTTP request
+	httpReq, err := http.NewRequestWithContext(ctx, req.Method, fullURL, bodyReader)
+	if err != nil {
+		return nil, "", retry.Permanent(fmt.Errorf("error creating request: %w", err))
+	}
+
+	// Add headers
+	for k, v := range r.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	var token string
+	if r.tokens != nil {
+		if token, err = r.tokens.Token(ctx); err != nil {
+			return nil, "", fmt.Errorf("error getting oauth2 token: %w", err)
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	for k, v := range req.Headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	// A assinatura cobre os headers e o corpo finais da requisição
+	if r.signer != nil {
+		if err := r.signer.Sign(httpReq, jsonBody); err != nil {
+			return nil, "", retry.Permanent(fmt.Errorf("error signing request: %w", err))
+		}
+	}
+
+	resp, err := r.client.Do(httpReq)
+	return resp, token, err
+}
+
 // Close implements the Close method of the Connector interface
 func (r *RestConnector) Close() error {
 	r.client.CloseIdleConnections()
@@ -117,9 +296,19 @@ func (r *RestConnector) Close() error {
 
 // Helper methods
 
-// buildURL constructs the full URL for the request
+// buildURL constructs the full URL for the request. Absolute URLs are used as
+// they are; other paths are relative to the base URL.
 func (r *RestConnector) buildURL(path string) string {
-	return fmt.Sprintf("%s%s", r.config.BaseURL, path)
+	if r.config.BaseURL == "" {
+		return path
+	}
+	if u, err := url.Parse(path); err == nil && u.IsAbs() {
+		return path
+	}
+	if path == "" {
+		return r.config.BaseURL
+	}
+	return strings.TrimSuffix(r.config.BaseURL, "/") + "/" + strings.TrimPrefix(path, "/")
 }
 
 // setupAuth configures authentication based on the config
@@ -128,8 +317,9 @@ func (r *RestConnector) setupAuth() error {
 	case "basic":
 		username := r.config.Auth.Credentials["username"]
 		password := r.config.Auth.Credentials["password"]
-		if username != "" && password != "" {
-			r.headers["Authorization"] = fmt.Sprintf("Basic %s:%s", username, password)
+		if username != "" {
+			credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+			r.headers["Authorization"] = "Basic " + credentials
 		}
 	case "bearer":
 		token := r.config.Auth.Credentials["token"]
@@ -145,38 +335,27 @@ func (r *RestConnector) setupAuth() error {
 			}
 			r.headers[keyName] = key
 		}
-	}
-	return nil
-}
-
-// executeWithRetry executes a request with retry logic
-func (r *RestConnector) executeWithRetry(req *http.Request, retryConfig RetryConfig) (*http.Response, error) {
-	var lastErr error
-	delay := retryConfig.RetryDelay
-	
-	for attempt := 0; attempt <= retryConfig.MaxRetries; attempt++ {
-		resp, err := r.client.Do(req)
-		if err == nil {
-			return resp, nil
-		}
-		
-		lastErr = err
-		
-		if attempt == retryConfig.MaxRetries {
-			break
-		}
-		
-		// Calculate next delay
-		if retryConfig.Multiplier > 0 {
-			delay = time.Duration(float64(delay) * retryConfig.Multiplier)
-			if delay > retryConfig.MaxDelay {
-				delay = retryConfig.MaxDelay
-			}
+	case "oauth2":
+		tokens, err := newTokenSource(r.client, r.config.Auth.Credentials)
+		if err != nil {
+			return err
+		}
+		r.tokens = tokens
+	case "hmac":
+		signer, err := newHMACSigner(r.config.Auth.Credentials)
+		if err != nil {
+			return err
 		}
-		
-		// Wait before next attempt
-		time.Sleep(delay)
+		r.signer = signer
+	case "aws_sigv4":
+		signer, err := newSigV4Signer(r.config.Auth.Credentials)
+		if err != nil {
+			return err
+		}
+		r.signer = signer
+	case "":
+	default:
+		return fmt.Errorf("unknown auth type: %s", r.config.Auth.Type)
 	}
-	
-	return nil, fmt.Errorf("maximum retry attempts reached: %w", lastErr)
+	return nil
 }
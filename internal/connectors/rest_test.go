@@ -0,0 +1,128 @@
+package connectors
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRestConnectorRetry(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		attempt := len(bodies)
+		mu.Unlock()
+
+		switch attempt {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{"ok": true}`))
+		}
+	}))
+	defer server.Close()
+
+	connector := NewRestConnector(Config{
+		BaseURL:     server.URL,
+		RetryConfig: RetryConfig{MaxRetries: 3, RetryDelay: time.Millisecond},
+	})
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+
+	resp, err := connector.Execute(context.Background(), Request{Method: "POST", URL: "/orders", Body: map[string]interface{}{"id": 1}})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Esperava sucesso após retentativas: %v %v", resp, err)
+	}
+
+	// O corpo é recriado a cada tentativa
+	for i, body := range bodies {
+		if body != `{"id":1}` {
+			t.Errorf("Corpo inesperado na tentativa %d: %q", i+1, body)
+		}
+	}
+	if len(resp.Attempts) != 3 || resp.Attempts[0].StatusCode != 429 || resp.Attempts[1].StatusCode != 503 {
+		t.Errorf("Tentativas inesperadas: %+v", resp.Attempts)
+	}
+}
+
+func TestRestConnectorRetryStopsOnClientErrors(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	connector := NewRestConnector(Config{RetryConfig: RetryConfig{MaxRetries: 3, RetryDelay: time.Millisecond}})
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+
+	// Respostas sem sucesso são devolvidas, não tratadas como erro do conector
+	resp, err := connector.Execute(context.Background(), Request{Method: "GET", URL: server.URL})
+	if err != nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Esperava a resposta 400: %v %v", resp, err)
+	}
+	if requests != 1 {
+		t.Errorf("Esperava 1 requisição, mas obteve %d", requests)
+	}
+
+	// Erros de conexão falham depois de esgotadas as tentativas
+	server.Close()
+	resp, err = connector.Execute(context.Background(), Request{Method: "GET", URL: server.URL})
+	if err == nil {
+		t.Fatalf("Esperava erro de conexão, mas obteve %v", resp)
+	}
+}
+
+// roundTripFunc adapta uma função a http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRestConnectorDefaultTimeout(t *testing.T) {
+	// Sem timeout configurado o cliente não corta a requisição
+	if timeout := NewRestConnector(Config{}).client.Timeout; timeout != 0 {
+		t.Errorf("Esperava o cliente sem timeout, obteve %v", timeout)
+	}
+
+	var remaining time.Duration
+	connector := NewRestConnector(Config{BaseURL: "http://api.test"})
+	connector.Connect(context.Background())
+	connector.client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		deadline, _ := r.Context().Deadline()
+		remaining = time.Until(deadline)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("{}")), Header: http.Header{}}, nil
+	})
+
+	// O prazo do step prevalece sobre o padrão de 30 segundos
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if _, err := connector.Execute(ctx, Request{Method: "GET", URL: "/"}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if remaining <= DefaultTimeout {
+		t.Errorf("Esperava o prazo do contexto, obteve %v", remaining)
+	}
+
+	// Sem prazo, o padrão limita cada tentativa
+	if _, err := connector.Execute(context.Background(), Request{Method: "GET", URL: "/"}); err != nil {
+		t.Fatalf("Erro inesperado: %v", err)
+	}
+	if remaining <= 0 || remaining > DefaultTimeout {
+		t.Errorf("Esperava o prazo padrão, obteve %v", remaining)
+	}
+}
@@ -0,0 +1,322 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package connectors
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requestSigner signs a request once its headers and body are final
+type requestSigner interface {
+	Sign(req *http.Request, body []byte) error
+}
+
+// hmacSigner signs requests with an HMAC of the method, the request URI, a
+// timestamp, a set of headers and a digest of the body. The string to sign is
+// those values joined by newlines:
+//
+//	POST
+//	/orders?page=2
+//	1700000000
+//	content-type:application/json
+//	<hex digest of the body>
+type hmacSigner struct {
+	key             []byte
+	algorithm       string
+	newHash         func() hash.Hash
+	header          string   // Header carrying the signature
+	prefix          string   // Prepended to the signature, e.g. "sha256="
+	encoding        string   // "hex" or "base64"
+	timestampHeader string   // Header carrying the Unix timestamp of the request
+	signedHeaders   []string // Headers included in the string to sign
+	digestHeader    string   // If set, header carrying the body digest, e.g. "SHA-256=<base64>"
+	now             func() time.Time
+}
+
+// hmacAlgorithms maps the supported algorithms to their hash functions
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+// newHMACSigner creates an HMAC signer from the credentials of an hmac auth
+// config: key, plus the optional algorithm (sha256), header (X-Signature),
+// prefix, encoding (hex), timestamp_header (X-Timestamp), signed_headers
+// (comma separated) and digest_header.
+func newHMACSigner(credentials map[string]string) (*hmacSigner, error) {
+	if credentials["key"] == "" {
+		return nil, fmt.Errorf("hmac requires credentials: key")
+	}
+
+	algorithm := strings.ToLower(valueOr(credentials["algorithm"], "sha256"))
+	newHash, ok := hmacAlgorithms[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown hmac algorithm: %s", algorithm)
+	}
+
+	encoding := valueOr(credentials["encoding"], "hex")
+	if encoding != "hex" && encoding != "base64" {
+		return nil, fmt.Errorf("unknown hmac encoding: %s", encoding)
+	}
+
+	var signedHeaders []string
+	for _, name := range strings.Split(credentials["signed_headers"], ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			signedHeaders = append(signedHeaders, name)
+		}
+	}
+
+	return &hmacSigner{
+		key:             []byte(credentials["key"]),
+		algorithm:       algorithm,
+		newHash:         newHash,
+		header:          valueOr(credentials["header"], "X-Signature"),
+		prefix:          credentials["prefix"],
+		encoding:        encoding,
+		timestampHeader: valueOr(credentials["timestamp_header"], "X-Timestamp"),
+		signedHeaders:   signedHeaders,
+		digestHeader:    credentials["digest_header"],
+		now:             time.Now,
+	}, nil
+}
+
+// Sign implements the requestSigner interface
+func (s *hmacSigner) Sign(req *http.Request, body []byte) error {
+	digest := s.newHash()
+	digest.Write(body)
+	bodyDigest := digest.Sum(nil)
+
+	if s.digestHeader != "" {
+		name := strings.ToUpper(strings.Replace(s.algorithm, "sha", "sha-", 1))
+		req.Header.Set(s.digestHeader, name+"="+base64.StdEncoding.EncodeToString(bodyDigest))
+	}
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set(s.timestampHeader, timestamp)
+
+	mac := hmac.New(s.newHash, s.key)
+	mac.Write([]byte(s.stringToSign(req, timestamp, bodyDigest)))
+	signature := mac.Sum(nil)
+
+	if s.encoding == "base64" {
+		req.Header.Set(s.header, s.prefix+base64.StdEncoding.EncodeToString(signature))
+	} else {
+		req.Header.Set(s.header, s.prefix+hex.EncodeToString(signature))
+	}
+	return nil
+}
+
+// stringToSign builds the string signed by the HMAC
+func (s *hmacSigner) stringToSign(req *http.Request, timestamp string, bodyDigest []byte) string {
+	lines := []string{req.Method, req.URL.RequestURI(), timestamp}
+	for _, name := range s.signedHeaders {
+		value := req.Header.Get(name)
+		if strings.EqualFold(name, "host") {
+			value = requestHost(req)
+		}
+		lines = append(lines, strings.ToLower(name)+":"+strings.TrimSpace(value))
+	}
+	lines = append(lines, hex.EncodeToString(bodyDigest))
+	return strings.Join(lines, "\n")
+}
+
+// sigV4Algorithm identifies AWS Signature Version 4 signed with HMAC-SHA256
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+// sigV4Signer signs requests with AWS Signature Version 4
+type sigV4Signer struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	service         string
+	now             func() time.Time
+}
+
+// newSigV4Signer creates an AWS SigV4 signer from the credentials of an
+// aws_sigv4 auth config: access_key_id, secret_access_key, region and service,
+// plus the optional session_token.
+func newSigV4Signer(credentials map[string]string) (*sigV4Signer, error) {
+	var missing []string
+	for _, key := range []string{"access_key_id", "secret_access_key", "region", "service"} {
+		if credentials[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("aws_sigv4 requires credentials: %s", strings.Join(missing, ", "))
+	}
+
+	return &sigV4Signer{
+		accessKeyID:     credentials["access_key_id"],
+		secretAccessKey: credentials["secret_access_key"],
+		sessionToken:    credentials["session_token"],
+		region:          credentials["region"],
+		service:         credentials["service"],
+		now:             time.Now,
+	}, nil
+}
+
+// sigV4UnsignedHeaders are left out of the signature because proxies and the
+// HTTP client may change them
+var sigV4UnsignedHeaders = map[string]bool{
+	"authorization":   true,
+	"user-agent":      true,
+	"x-amzn-trace-id": true,
+}
+
+// Sign implements the requestSigner interface
+func (s *sigV4Signer) Sign(req *http.Request, body []byte) error {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := strings.Join([]string{now.Format("20060102"), s.region, s.service, "aws4_request"}, "/")
+
+	payloadHash := sha256Hex(body)
+	req.Header.Del("Authorization")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+	}
+	if s.service == "s3" {
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+
+	canonicalHeaders, signedHeaders := s.canonicalHeaders(req)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s.canonicalURI(req.URL),
+		canonicalQuery(req.URL),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, s.service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.accessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// canonicalURI returns the URI-encoded path. Services other than S3 encode
+// the already escaped path once more.
+func (s *sigV4Signer) canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	if s.service == "s3" {
+		return path
+	}
+	return uriEncode(path, false)
+}
+
+// canonicalHeaders returns the canonical headers block and the list of signed
+// headers: the host plus every header of the request, lowercased and sorted.
+func (s *sigV4Signer) canonicalHeaders(req *http.Request) (string, string) {
+	values := map[string]string{"host": requestHost(req)}
+	for name, headerValues := range req.Header {
+		name = strings.ToLower(name)
+		if sigV4UnsignedHeaders[name] {
+			continue
+		}
+		trimmed := make([]string, len(headerValues))
+		for i, value := range headerValues {
+			trimmed[i] = strings.Join(strings.Fields(value), " ")
+		}
+		values[name] = strings.Join(trimmed, ",")
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var block strings.Builder
+	for _, name := range names {
+		block.WriteString(name + ":" + values[name] + "\n")
+	}
+	return block.String(), strings.Join(names, ";")
+}
+
+// canonicalQuery returns the query parameters URI-encoded and sorted by name
+// and value
+func canonicalQuery(u *url.URL) string {
+	query := u.Query()
+	params := make([]string, 0, len(query))
+	for name, values := range query {
+		for _, value := range values {
+			params = append(params, uriEncode(name, true)+"="+uriEncode(value, true))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// uriEncode encodes every byte except the unreserved characters of RFC 3986
+// and, unless encodeSlash is set, the slash
+func uriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			encoded.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			encoded.WriteByte(c)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", c)
+		}
+	}
+	return encoded.String()
+}
+
+// requestHost returns the host the request is sent to
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// valueOr returns value, or fallback if value is empty
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
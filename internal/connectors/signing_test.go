@@ -0,0 +1,158 @@
+package connectors
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Credenciais e data da suíte de testes da AWS Signature Version 4
+var sigV4TestCredentials = map[string]string{
+	"access_key_id":     "AKIDEXAMPLE",
+	"secret_access_key": "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	"region":            "us-east-1",
+	"service":           "service",
+}
+
+func TestSigV4TestSuite(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		url       string
+		signature string
+	}{
+		{"get-vanilla", "GET", "https://example.amazonaws.com/", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"post-vanilla", "POST", "https://example.amazonaws.com/", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+	}
+
+	signer, err := newSigV4Signer(sigV4TestCredentials)
+	if err != nil {
+		t.Fatalf("Erro ao criar signer: %v", err)
+	}
+	signer.now = func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, tt.url, nil)
+			if err := signer.Sign(req, nil); err != nil {
+				t.Fatalf("Erro ao assinar: %v", err)
+			}
+
+			expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=" + tt.signature
+			if got := req.Header.Get("Authorization"); got != expected {
+				t.Errorf("Authorization inesperado:\n%s\nesperado:\n%s", got, expected)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date inesperado: %s", got)
+			}
+		})
+	}
+}
+
+func TestHMACSigner(t *testing.T) {
+	signer, err := newHMACSigner(map[string]string{
+		"key":            "key",
+		"signed_headers": "host, content-type",
+		"prefix":         "sha256=",
+		"digest_header":  "Digest",
+	})
+	if err != nil {
+		t.Fatalf("Erro ao criar signer: %v", err)
+	}
+	signer.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	body := []byte(`{"id":1}`)
+	req, _ := http.NewRequest("POST", "https://api.example.com/orders?page=2", nil)
+	req.Header.Set("Content-Type", "application/json")
+	if err := signer.Sign(req, body); err != nil {
+		t.Fatalf("Erro ao assinar: %v", err)
+	}
+
+	bodyDigest := sha256.Sum256(body)
+	stringToSign := strings.Join([]string{
+		"POST",
+		"/orders?page=2",
+		"1700000000",
+		"host:api.example.com",
+		"content-type:application/json",
+		hex.EncodeToString(bodyDigest[:]),
+	}, "\n")
+	if got := signer.stringToSign(req, "1700000000", bodyDigest[:]); got != stringToSign {
+		t.Errorf("String a assinar inesperada:\n%s", got)
+	}
+
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte(stringToSign))
+	if got, expected := req.Header.Get("X-Signature"), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != expected {
+		t.Errorf("Assinatura inesperada: %s, esperada %s", got, expected)
+	}
+	if got := req.Header.Get("X-Timestamp"); got != "1700000000" {
+		t.Errorf("Timestamp inesperado: %s", got)
+	}
+	if got := req.Header.Get("Digest"); got != "SHA-256=A3ySFO73TMOIfzpPCFtOF9digNr9JzsO4WDAnEuhz9Q=" {
+		t.Errorf("Digest inesperado: %s", got)
+	}
+}
+
+func TestHMACAlgorithmVector(t *testing.T) {
+	// Vetor conhecido de HMAC-SHA256 e HMAC-SHA1 para a chave "key"
+	message := []byte("The quick brown fox jumps over the lazy dog")
+	vectors := map[string]string{
+		"sha256": "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		"sha1":   "de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9",
+	}
+	for algorithm, expected := range vectors {
+		mac := hmac.New(hmacAlgorithms[algorithm], []byte("key"))
+		mac.Write(message)
+		if got := hex.EncodeToString(mac.Sum(nil)); got != expected {
+			t.Errorf("HMAC %s inesperado: %s", algorithm, got)
+		}
+	}
+
+	if _, err := newHMACSigner(map[string]string{"key": "key", "algorithm": "md5"}); err == nil {
+		t.Error("Esperava erro para algoritmo desconhecido")
+	}
+}
+
+func TestRestConnectorSignsFinalRequest(t *testing.T) {
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get("X-Signature")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	connector := NewRestConnector(Config{
+		BaseURL: server.URL,
+		Auth:    AuthConfig{Type: "hmac", Credentials: map[string]string{"key": "secret", "signed_headers": "x-request-id"}},
+	})
+	if err := connector.Connect(context.Background()); err != nil {
+		t.Fatalf("Erro ao conectar: %v", err)
+	}
+	connector.signer.(*hmacSigner).now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	_, err := connector.Execute(context.Background(), Request{
+		Method:  "POST",
+		URL:     "/orders",
+		Headers: map[string]string{"X-Request-ID": "42"},
+		Body:    map[string]interface{}{"id": 1},
+	})
+	if err != nil {
+		t.Fatalf("Erro na requisição: %v", err)
+	}
+
+	digest := sha256.Sum256([]byte(`{"id":1}`))
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("POST\n/orders\n1700000000\nx-request-id:42\n" + hex.EncodeToString(digest[:])))
+	if expected := hex.EncodeToString(mac.Sum(nil)); signature != expected {
+		t.Errorf("Assinatura inesperada: %s, esperada %s", signature, expected)
+	}
+}
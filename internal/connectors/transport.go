@@ -0,0 +1,133 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package connectors
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TLSConfig configures the TLS connections of a connector. Certificates and
+// keys are PEM encoded and can be given inline or as file paths.
+type TLSConfig struct {
+	CertFile string `json:"cert_file,omitempty"` // Client certificate, for mutual TLS
+	KeyFile  string `json:"key_file,omitempty"`  // Key of the client certificate
+	CAFile   string `json:"ca_file,omitempty"`   // CA bundle used instead of the system roots
+	Cert     string `json:"cert,omitempty"`      // Inline alternative to CertFile
+	Key      string `json:"key,omitempty"`       // Inline alternative to KeyFile
+	CA       string `json:"ca,omitempty"`        // Inline alternative to CAFile
+
+	ServerName string `json:"server_name,omitempty"` // Name used to verify the server certificate
+	MinVersion string `json:"min_version,omitempty"` // "1.0", "1.1", "1.2" (default) or "1.3"
+
+	// InsecureSkipVerify disables the verification of the server certificate.
+	// Only meant for development against servers with self-signed certificates.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// tlsVersions maps the accepted MinVersion values to TLS versions
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// newTransport creates the HTTP transport of a connector from its TLS and
+// proxy settings. Without a proxy, the proxy is taken from the environment
+// (HTTP_PROXY, HTTPS_PROXY and NO_PROXY).
+func newTransport(config Config) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.Proxy != "" {
+		proxyURL, err := url.Parse(config.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL: %s", config.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if config.TLS != nil {
+		tlsConfig, err := config.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+// build creates the tls.Config described by the settings
+func (c *TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.ServerName,
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	}
+
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS version: %s", c.MinVersion)
+		}
+		tlsConfig.MinVersion = version
+	}
+
+	certPEM, err := pemValue(c.Cert, c.CertFile, "client certificate")
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := pemValue(c.Key, c.KeyFile, "client key")
+	if err != nil {
+		return nil, err
+	}
+	if (certPEM == nil) != (keyPEM == nil) {
+		return nil, fmt.Errorf("client certificate and key must be set together")
+	}
+	if certPEM != nil {
+		certificate, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	caPEM, err := pemValue(c.CA, c.CAFile, "CA bundle")
+	if err != nil {
+		return nil, err
+	}
+	if caPEM != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("CA bundle has no valid certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// pemValue returns the inline PEM value or, if it is empty, the content of
+// the file. It returns nil if neither is set.
+func pemValue(inline, file, name string) ([]byte, error) {
+	if inline != "" && file != "" {
+		return nil, fmt.Errorf("%s cannot be set both inline and as a file", name)
+	}
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if file == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", name, err)
+	}
+	return data, nil
+}
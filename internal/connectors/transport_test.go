@@ -0,0 +1,214 @@
+package connectors
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCertificate é um certificado gerado para os testes, em PEM
+type testCertificate struct {
+	cert, key []byte
+	x509      *x509.Certificate
+	signer    *ecdsa.PrivateKey
+}
+
+// newTestCertificate gera um certificado assinado por parent, ou autoassinado
+// se parent for nil
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, usage x509.ExtKeyUsage) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Erro ao gerar chave: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		DNSNames:     []string{name},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	issuer, issuerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{usage}
+		issuer, issuerKey = parent.x509, parent.signer
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("Erro ao gerar certificado: %v", err)
+	}
+	parsed, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	return &testCertificate{
+		cert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		key:    pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		x509:   parsed,
+		signer: key,
+	}
+}
+
+func (c *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(c.cert, c.key)
+	if err != nil {
+		t.Fatalf("Erro ao carregar certificado: %v", err)
+	}
+	return certificate
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Erro ao escrever %s: %v", name, err)
+	}
+	return path
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(`{"ok": true}`))
+}
+
+// get executa uma requisição GET por um conector com a configuração dada
+func get(config Config, url string) (*Response, error) {
+	connector := NewRestConnector(config)
+	if err := connector.Connect(context.Background()); err != nil {
+		return nil, err
+	}
+	defer connector.Close()
+	return connector.Execute(context.Background(), Request{Method: "GET", URL: url})
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newTestCertificate(t, "goflow-test-ca", nil, 0)
+	serverCert := newTestCertificate(t, "api.internal", ca, x509.ExtKeyUsageServerAuth)
+	clientCert := newTestCertificate(t, "goflow-client", ca, x509.ExtKeyUsageClientAuth)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.x509)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert.tlsCertificate(t)},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := writeFile(t, "ca.pem", ca.cert)
+
+	// Sem certificado de cliente o servidor recusa o handshake
+	if _, err := get(Config{TLS: &TLSConfig{CAFile: caFile, ServerName: "api.internal"}}, server.URL); err == nil {
+		t.Error("Esperava erro sem certificado de cliente")
+	}
+
+	config := Config{TLS: &TLSConfig{
+		CertFile:   writeFile(t, "client.pem", clientCert.cert),
+		KeyFile:    writeFile(t, "client-key.pem", clientCert.key),
+		CAFile:     caFile,
+		ServerName: "api.internal",
+	}}
+	resp, err := get(config, server.URL)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Esperava sucesso com certificado de cliente: %v %v", resp, err)
+	}
+
+	// Certificados em linha funcionam como os arquivos
+	config.TLS = &TLSConfig{Cert: string(clientCert.cert), Key: string(clientCert.key), CA: string(ca.cert), ServerName: "api.internal"}
+	if _, err := get(config, server.URL); err != nil {
+		t.Errorf("Esperava sucesso com certificados em linha: %v", err)
+	}
+
+	// O nome do servidor precisa corresponder ao certificado
+	config.TLS.ServerName = "other.internal"
+	if _, err := get(config, server.URL); err == nil {
+		t.Error("Esperava erro com server_name diferente do certificado")
+	}
+}
+
+func TestTLSVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+
+	// O certificado do httptest não é confiável para as raízes do sistema
+	if _, err := get(Config{}, server.URL); err == nil {
+		t.Error("Esperava erro de verificação do certificado")
+	}
+
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if _, err := get(Config{TLS: &TLSConfig{CA: string(ca)}}, server.URL); err != nil {
+		t.Errorf("Esperava sucesso com o CA do servidor: %v", err)
+	}
+
+	if _, err := get(Config{TLS: &TLSConfig{InsecureSkipVerify: true}}, server.URL); err != nil {
+		t.Errorf("Esperava sucesso com insecure_skip_verify: %v", err)
+	}
+
+	if _, err := get(Config{TLS: &TLSConfig{CA: "not a certificate"}}, server.URL); err == nil {
+		t.Error("Esperava erro com CA inválido")
+	}
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.TLS = &tls.Config{MaxVersion: tls.VersionTLS12}
+	server.StartTLS()
+	defer server.Close()
+
+	config := Config{TLS: &TLSConfig{InsecureSkipVerify: true, MinVersion: "1.2"}}
+	if _, err := get(config, server.URL); err != nil {
+		t.Errorf("Esperava sucesso com TLS 1.2: %v", err)
+	}
+
+	config.TLS.MinVersion = "1.3"
+	if _, err := get(config, server.URL); err == nil {
+		t.Error("Esperava erro exigindo TLS 1.3 de um servidor TLS 1.2")
+	}
+
+	config.TLS.MinVersion = "2.0"
+	if _, err := get(config, server.URL); err == nil {
+		t.Error("Esperava erro com versão de TLS desconhecida")
+	}
+}
+
+func TestProxy(t *testing.T) {
+	requested := make(chan string, 1)
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- r.URL.String()
+		okHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	resp, err := get(Config{Proxy: proxy.URL, BaseURL: "http://backend.invalid/api"}, "/items")
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Esperava sucesso pelo proxy: %v %v", resp, err)
+	}
+	if url := <-requested; url != "http://backend.invalid/api/items" {
+		t.Errorf("Proxy recebeu URL inesperada: %s", url)
+	}
+
+	if _, err := get(Config{Proxy: "://bad"}, "http://backend.invalid"); err == nil {
+		t.Error("Esperava erro com URL de proxy inválida")
+	}
+}
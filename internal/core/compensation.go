@@ -0,0 +1,67 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"context"
+	"log"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// compensationSteps returns the IDs of the steps used as the compensation of
+// another step.
+func compensationSteps(workflow *models.Workflow) map[string]bool {
+	compensations := make(map[string]bool)
+	for _, step := range workflow.Steps {
+		if step.Compensate != "" {
+			compensations[step.Compensate] = true
+		}
+	}
+	return compensations
+}
+
+// compensate runs the compensation of every completed step of a failed run, in
+// reverse completion order. Compensations run even if the run timed out, and a
+// failed compensation does not stop the others. Their results are recorded in
+// the Compensations of the run result; steps already compensated, such as
+// those of a resumed run, are not compensated again.
+func (w *WorkflowEngine) compensate(ctx context.Context, workflow *models.Workflow, execCtx *ExecutionContext, run *models.Run, completed []string) {
+	if ctx.Err() != nil {
+		ctx = context.WithoutCancel(ctx)
+	}
+
+	steps := make(map[string]models.Step, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		steps[step.ID] = step
+	}
+
+	w.mu.RLock()
+	compensated := make(map[string]bool, len(run.Result.Compensations))
+	for _, compensation := range run.Result.Compensations {
+		compensated[compensation.StepID] = true
+	}
+	w.mu.RUnlock()
+
+	for i := len(completed) - 1; i >= 0; i-- {
+		step := steps[completed[i]]
+		if step.Compensate == "" || compensated[step.ID] {
+			continue
+		}
+
+		result, err := w.executeStep(ctx, steps[step.Compensate], execCtx)
+		if err != nil {
+			log.Printf("error compensating step %s of run %s: %v", step.ID, run.ID, err)
+		}
+
+		w.mu.Lock()
+		run.Result.Compensations = append(run.Result.Compensations, models.CompensationResult{
+			StepID: step.ID,
+			Result: result,
+		})
+		w.mu.Unlock()
+		w.checkpointRun(run)
+	}
+}
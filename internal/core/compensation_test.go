@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// recordStep registra os steps executados e falha quando configurado com "fail"
+type recordStep struct {
+	mu       *sync.Mutex
+	executed *[]string
+	id       string
+	fail     bool
+}
+
+func (s *recordStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	s.mu.Lock()
+	*s.executed = append(*s.executed, s.id)
+	s.mu.Unlock()
+
+	if s.fail {
+		return nil, fmt.Errorf("step %s failed", s.id)
+	}
+	return &models.StepResult{Status: models.StatusCompleted, Data: s.id}, nil
+}
+
+func TestCompensation(t *testing.T) {
+	var mu sync.Mutex
+	var executed []string
+
+	engine := NewWorkflowEngine()
+	err := engine.RegisterStepType("record", func(config models.StepConfig) (steps.StepExecutor, error) {
+		fail, _ := config["fail"].(bool)
+		return &recordStep{mu: &mu, executed: &executed, id: config["id"].(string), fail: fail}, nil
+	})
+	if err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+
+	record := func(id, next, compensate string, fail bool) models.Step {
+		step := models.Step{
+			ID:         id,
+			Type:       "record",
+			Config:     map[string]interface{}{"id": id, "fail": fail},
+			Compensate: compensate,
+			Required:   true,
+		}
+		if next != "" {
+			step.Next = []models.Transition{{Step: next}}
+		}
+		return step
+	}
+
+	workflow := &models.Workflow{
+		ID:   "saga",
+		Name: "Saga",
+		Steps: []models.Step{
+			record("create-order", "reserve-stock", "cancel-order", false),
+			record("reserve-stock", "charge", "release-stock", false),
+			record("charge", "", "refund", true),
+			record("cancel-order", "", "", false),
+			record("release-stock", "", "", false),
+			record("refund", "", "", false),
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err == nil {
+		t.Fatal("Esperava erro ao executar workflow")
+	}
+
+	// Só os steps concluídos são compensados, na ordem inversa
+	expected := []string{"create-order", "reserve-stock", "charge", "release-stock", "cancel-order"}
+	if !reflect.DeepEqual(executed, expected) {
+		t.Errorf("Esperava execução %v, mas obteve %v", expected, executed)
+	}
+
+	if len(result.Compensations) != 2 {
+		t.Fatalf("Esperava 2 compensações, mas obteve %v", result.Compensations)
+	}
+	for i, stepID := range []string{"reserve-stock", "create-order"} {
+		compensation := result.Compensations[i]
+		if compensation.StepID != stepID || compensation.Result.Status != models.StatusCompleted {
+			t.Errorf("Compensação %d inesperada: %+v", i, compensation)
+		}
+	}
+
+	// Steps de compensação não fazem parte da execução normal
+	for _, stepID := range []string{"cancel-order", "release-stock", "refund"} {
+		if _, exists := result.StepResults[stepID]; exists {
+			t.Errorf("Step de compensação %s não deveria estar nos resultados dos steps", stepID)
+		}
+	}
+}
+
+func TestCompensationValidation(t *testing.T) {
+	engine := NewWorkflowEngine()
+	echo := func(id string) models.Step {
+		return models.Step{ID: id, Type: "echo", Config: map[string]interface{}{"message": id}}
+	}
+
+	start := echo("start")
+	start.Next = []models.Transition{{Step: "undo"}}
+	start.Compensate = "undo"
+	missing := echo("missing-ref")
+	missing.Compensate = "nope"
+
+	workflow := &models.Workflow{ID: "invalid-saga", Steps: []models.Step{start, echo("undo"), missing}}
+	err := engine.ValidateWorkflow(workflow)
+	if err == nil {
+		t.Fatal("Esperava erro de validação")
+	}
+
+	for _, message := range []string{
+		"step start: compensation step undo cannot be used as a next or on_error step",
+		"step missing-ref: compensate step nope does not exist",
+	} {
+		if !containsValidationError(err, message) {
+			t.Errorf("Esperava erro %q, erros: %v", message, err)
+		}
+	}
+}
+
+// containsValidationError verifica se a lista de erros contém a mensagem informada
+func containsValidationError(err error, message string) bool {
+	validationErrs, ok := err.(ValidationErrors)
+	if !ok {
+		return false
+	}
+	for _, validationErr := range validationErrs {
+		if validationErr.Error() == message {
+			return true
+		}
+	}
+	return false
+}
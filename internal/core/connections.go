@@ -0,0 +1,251 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/carloskvasir/goflow/internal/connectors"
+	"github.com/carloskvasir/goflow/internal/expression"
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// connectionKey identifies a connector of the pool. Global connections, and the
+// default connection used by steps that reference none, have no workflow ID.
+type connectionKey struct {
+	workflowID string
+	name       string
+}
+
+// connectionPool holds the connectors opened by the engine, so that the steps
+// referencing the same connection share its HTTP client and credentials.
+// Connectors are connected on first use and closed when the workflow that
+// declares them is replaced or deleted, or when the engine is closed.
+type connectionPool struct {
+	mu         sync.Mutex
+	global     map[string]connectors.Config
+	connectors map[connectionKey]connectors.Connector
+	breakers   *connectors.CircuitBreakers // Shared by every connector, so they outlive reconnections
+	limiters   *connectors.RateLimiters    // Shared by every connector, so quotas hold across workflows
+}
+
+// String returns the name of the connection, prefixed by the ID of the workflow
+// that declares it
+func (k connectionKey) String() string {
+	if k.workflowID == "" {
+		return k.name
+	}
+	return k.workflowID + "/" + k.name
+}
+
+func newConnectionPool() *connectionPool {
+	return &connectionPool{
+		global:     make(map[string]connectors.Config),
+		connectors: make(map[connectionKey]connectors.Connector),
+		breakers:   connectors.NewCircuitBreakers(),
+		limiters:   connectors.NewRateLimiters(),
+	}
+}
+
+// WithConnections sets the global named connections, available to the rest
+// steps of every workflow.
+func WithConnections(connections map[string]connectors.Config) EngineOption {
+	return func(w *WorkflowEngine) {
+		for name, config := range connections {
+			w.connections.global[name] = config
+		}
+	}
+}
+
+// lookup returns the config of a named connection, declared by the workflow
+// or globally, and the key of its connector in the pool.
+func (p *connectionPool) lookup(workflow *models.Workflow, name string) (connectors.Config, connectionKey, bool) {
+	if name == "" {
+		return connectors.Config{}, connectionKey{}, true
+	}
+	if workflow != nil {
+		if config, exists := workflow.Connections[name]; exists {
+			return config, connectionKey{workflowID: workflow.ID, name: name}, true
+		}
+	}
+	config, exists := p.global[name]
+	return config, connectionKey{name: name}, exists
+}
+
+// hasConnection reports whether a connection is declared by the workflow or globally.
+func (p *connectionPool) hasConnection(workflow *models.Workflow, name string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, _, exists := p.lookup(workflow, name)
+	return exists
+}
+
+// get returns the connector of a connection, connecting it on first use. The
+// strings of the connection config are rendered as templates against globals,
+// so that credentials can come from {{ secrets.name }}.
+func (p *connectionPool) get(ctx context.Context, workflow *models.Workflow, name string, globals map[string]interface{}) (connectors.Connector, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	config, key, exists := p.lookup(workflow, name)
+	if !exists {
+		return nil, fmt.Errorf("connection %s not found", name)
+	}
+	if connector, exists := p.connectors[key]; exists {
+		return connector, nil
+	}
+
+	config, err := renderConnection(config, globals)
+	if err != nil {
+		return nil, fmt.Errorf("connection %s: %w", name, err)
+	}
+
+	connector := connectors.NewRestConnector(config)
+	connector.UseCircuitBreakers(p.breakers, key.String())
+	connector.UseRateLimiters(p.limiters, key.String())
+	if err := connector.Connect(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting %s: %w", name, err)
+	}
+	p.connectors[key] = connector
+	return connector, nil
+}
+
+// closeWorkflow closes the connectors of the connections declared by a workflow
+// and drops their circuit breakers and rate limiters, which are built again
+// from the new config.
+func (p *connectionPool) closeWorkflow(workflowID string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, connector := range p.connectors {
+		if key.workflowID == workflowID {
+			connector.Close()
+			delete(p.connectors, key)
+		}
+	}
+	p.breakers.Reset(workflowID + "/")
+	p.limiters.Reset(workflowID + "/")
+}
+
+// close closes every connector of the pool.
+func (p *connectionPool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var firstErr error
+	for key, connector := range p.connectors {
+		if err := connector.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("error closing connection %s: %w", key.name, err)
+		}
+		delete(p.connectors, key)
+	}
+	return firstErr
+}
+
+// renderConnection renders the templates of the base URL, default headers,
+// credentials, proxy and inline TLS certificates of a connection config.
+func renderConnection(config connectors.Config, globals map[string]interface{}) (connectors.Config, error) {
+	render := func(field, template string) (string, error) {
+		value, err := expression.Render(template, globals)
+		if err != nil {
+			return "", &expression.FieldError{Field: field, Err: err}
+		}
+		return fmt.Sprintf("%v", value), nil
+	}
+
+	var err error
+	if config.BaseURL, err = render("base_url", config.BaseURL); err != nil {
+		return config, err
+	}
+
+	headers := make(map[string]string, len(config.Headers))
+	for key, value := range config.Headers {
+		if headers[key], err = render("headers."+key, value); err != nil {
+			return config, err
+		}
+	}
+	config.Headers = headers
+
+	credentials := make(map[string]string, len(config.Auth.Credentials))
+	for key, value := range config.Auth.Credentials {
+		if credentials[key], err = render("auth.credentials."+key, value); err != nil {
+			return config, err
+		}
+	}
+	config.Auth.Credentials = credentials
+
+	if config.Proxy, err = render("proxy", config.Proxy); err != nil {
+		return config, err
+	}
+
+	// Certificados e chaves em linha podem vir dos segredos
+	if config.TLS != nil {
+		tlsConfig := *config.TLS
+		if tlsConfig.Cert, err = render("tls.cert", tlsConfig.Cert); err != nil {
+			return config, err
+		}
+		if tlsConfig.Key, err = render("tls.key", tlsConfig.Key); err != nil {
+			return config, err
+		}
+		if tlsConfig.CA, err = render("tls.ca", tlsConfig.CA); err != nil {
+			return config, err
+		}
+		config.TLS = &tlsConfig
+	}
+	return config, nil
+}
+
+// CircuitBreakers returns the state of the circuit breakers of the connections,
+// keyed by connection name, or by host for breakers scoped by host.
+func (w *WorkflowEngine) CircuitBreakers() []connectors.CircuitStatus {
+	return w.connections.breakers.Status()
+}
+
+// connectStep hands a step that executes through a connector the connector of
+// the connection it references. A step that retries on its own gets the
+// connector without the retries of the connection, so that the attempts and
+// the Retry-After waits of both are not multiplied.
+func (w *WorkflowEngine) connectStep(ctx context.Context, step models.Step, executor steps.StepExecutor) error {
+	user, ok := executor.(steps.ConnectionUser)
+	if !ok {
+		return nil
+	}
+
+	// O workflow da execução mais interna define as conexões visíveis ao step
+	var workflowID string
+	if stack := runStack(ctx); len(stack) > 0 {
+		workflowID = stack[len(stack)-1].workflowID
+	}
+
+	w.mu.RLock()
+	workflow := w.workflows[workflowID]
+	w.mu.RUnlock()
+
+	connector, err := w.connections.get(ctx, workflow, user.ConnectionName(), w.templateGlobals())
+	if err != nil {
+		return err
+	}
+	if step.Retry != nil && step.Retry.MaxAttempts > 1 {
+		connector = singleAttemptConnector{connector}
+	}
+	user.UseConnector(connector)
+	return nil
+}
+
+// singleAttemptConnector sends each request once, ignoring the retry config of
+// its connection
+type singleAttemptConnector struct {
+	connectors.Connector
+}
+
+// Execute performs a request without retries
+func (c singleAttemptConnector) Execute(ctx context.Context, req connectors.Request) (*connectors.Response, error) {
+	req.RetryConfig = &connectors.RetryConfig{}
+	return c.Connector.Execute(ctx, req)
+}
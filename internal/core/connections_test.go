@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/connectors"
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+func TestRestStepConnections(t *testing.T) {
+	type request struct {
+		server, path, authorization, client string
+	}
+	requests := make(chan request, 10)
+	newServer := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests <- request{name, r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("X-Client")}
+			w.Write([]byte(`{"ok": true}`))
+		}))
+	}
+	global := newServer("global")
+	defer global.Close()
+	local := newServer("local")
+	defer local.Close()
+
+	engine := NewWorkflowEngine(
+		WithSecrets(map[string]string{"token": "abc"}),
+		WithConnections(map[string]connectors.Config{
+			"api": {
+				BaseURL: global.URL + "/v1",
+				Auth:    connectors.AuthConfig{Type: "bearer", Credentials: map[string]string{"token": "{{ secrets.token }}"}},
+				Headers: map[string]string{"X-Client": "goflow"},
+			},
+			"other": {BaseURL: global.URL},
+		}),
+	)
+	defer engine.Close()
+
+	workflow := &models.Workflow{
+		ID: "connected",
+		Connections: map[string]connectors.Config{
+			// Conexões do workflow têm precedência sobre as globais
+			"other": {BaseURL: local.URL},
+		},
+		Steps: []models.Step{
+			{ID: "first", Type: "rest", Config: map[string]interface{}{"connection": "api", "method": "GET", "url": "/users"}, Next: []models.Transition{{Step: "second"}}},
+			{ID: "second", Type: "rest", Config: map[string]interface{}{"connection": "other", "method": "GET", "url": "/status"}, Next: []models.Transition{{Step: "third"}}},
+			{ID: "third", Type: "rest", Config: map[string]interface{}{"method": "GET", "url": global.URL + "/direct"}},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	if _, err := engine.ExecuteWorkflow(context.Background(), workflow.ID); err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	expected := []request{
+		{"global", "/v1/users", "Bearer abc", "goflow"},
+		{"local", "/status", "", ""},
+		{"global", "/direct", "", ""},
+	}
+	for _, want := range expected {
+		if got := <-requests; got != want {
+			t.Errorf("Esperava requisição %+v, mas obteve %+v", want, got)
+		}
+	}
+
+	// Steps que referenciam conexões inexistentes são rejeitados no registro
+	workflow = &models.Workflow{
+		ID:    "unknown",
+		Steps: []models.Step{{ID: "call", Type: "rest", Config: map[string]interface{}{"connection": "missing", "method": "GET", "url": "/"}}},
+	}
+	var validationErrs ValidationErrors
+	if !errors.As(engine.RegisterWorkflow(workflow), &validationErrs) {
+		t.Fatal("Esperava ValidationErrors para conexão inexistente")
+	}
+}
+
+func TestConnectionRateLimitReregister(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	engine := NewWorkflowEngine()
+	defer engine.Close()
+
+	register := func(limit connectors.RateLimitConfig) {
+		workflow := &models.Workflow{
+			ID:          "limited",
+			Connections: map[string]connectors.Config{"api": {BaseURL: server.URL, RateLimit: &limit}},
+			Steps:       []models.Step{{ID: "call", Type: "rest", Config: map[string]interface{}{"connection": "api", "method": "GET", "url": "/"}}},
+		}
+		if err := engine.RegisterWorkflow(workflow); err != nil {
+			t.Fatalf("Erro ao registrar workflow: %v", err)
+		}
+	}
+
+	register(connectors.RateLimitConfig{Rate: 0.001, Policy: connectors.RateLimitFail})
+	execute := func() models.StepResult {
+		result, err := engine.ExecuteWorkflow(context.Background(), "limited")
+		if err != nil {
+			t.Fatalf("Erro ao executar workflow: %v", err)
+		}
+		return result.StepResults["call"]
+	}
+	execute()
+	if result := execute(); result.Status != models.StatusFailed || !strings.Contains(result.Error, "rate limit exceeded") {
+		t.Fatalf("Esperava o limite excedido: %+v", result)
+	}
+
+	// O novo limite vale assim que o workflow é registrado de novo
+	if err := engine.DeleteWorkflow("limited"); err != nil {
+		t.Fatalf("Erro ao remover workflow: %v", err)
+	}
+	register(connectors.RateLimitConfig{Rate: 1000})
+	for i := 0; i < 3; i++ {
+		if result := execute(); result.Status != models.StatusCompleted {
+			t.Fatalf("Erro após alterar o limite: %+v", result)
+		}
+	}
+}
+
+func TestConnectionCircuitBreakerReregister(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	engine := NewWorkflowEngine()
+	defer engine.Close()
+
+	register := func(threshold int) {
+		workflow := &models.Workflow{
+			ID: "guarded",
+			Connections: map[string]connectors.Config{"api": {
+				BaseURL:        server.URL,
+				CircuitBreaker: &connectors.CircuitBreakerConfig{FailureThreshold: threshold},
+			}},
+			Steps: []models.Step{{ID: "call", Type: "rest", Config: map[string]interface{}{"connection": "api", "method": "GET", "url": "/"}}},
+		}
+		if err := engine.RegisterWorkflow(workflow); err != nil {
+			t.Fatalf("Erro ao registrar workflow: %v", err)
+		}
+	}
+
+	register(1)
+	engine.ExecuteWorkflow(context.Background(), "guarded")
+	if status := engine.CircuitBreakers(); len(status) != 1 || status[0].State != connectors.CircuitOpen {
+		t.Fatalf("Esperava o circuito aberto: %+v", status)
+	}
+
+	// O workflow registrado de novo começa com o circuito fechado e o novo limite
+	if err := engine.DeleteWorkflow("guarded"); err != nil {
+		t.Fatalf("Erro ao remover workflow: %v", err)
+	}
+	register(3)
+	if status := engine.CircuitBreakers(); len(status) != 0 {
+		t.Fatalf("Esperava os circuitos descartados: %+v", status)
+	}
+	for i := 0; i < 2; i++ {
+		engine.ExecuteWorkflow(context.Background(), "guarded")
+	}
+	if status := engine.CircuitBreakers(); len(status) != 1 || status[0].State != connectors.CircuitClosed || status[0].Failures != 2 {
+		t.Errorf("Esperava o circuito fechado com 2 falhas: %+v", status)
+	}
+}
+
+func TestRestStepRetriesOnce(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	engine := NewWorkflowEngine()
+	defer engine.Close()
+
+	workflow := &models.Workflow{
+		ID: "retried",
+		Connections: map[string]connectors.Config{"api": {
+			BaseURL:     server.URL,
+			RetryConfig: connectors.RetryConfig{MaxRetries: 2, RetryDelay: time.Millisecond},
+		}},
+		Steps: []models.Step{{
+			ID:     "call",
+			Type:   "rest",
+			Config: map[string]interface{}{"connection": "api", "method": "GET", "url": "/"},
+			Retry:  &models.RetryConfig{MaxAttempts: 2, Delay: time.Millisecond},
+		}},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+	engine.ExecuteWorkflow(context.Background(), workflow.ID)
+
+	// As retentativas do step substituem as da conexão em vez de se multiplicarem
+	if requests.Load() != 2 {
+		t.Errorf("Esperava 2 requisições, obteve %d", requests.Load())
+	}
+}
@@ -0,0 +1,124 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"sync"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// ExecutionContext accumulates the data produced during a workflow run so that
+// every step can consume the results of the steps that completed before it.
+type ExecutionContext struct {
+	WorkflowID string
+	RunID      string
+	Inputs     map[string]interface{}
+	Metadata   map[string]interface{}
+
+	// Variables are extra values exposed at the top level, such as the
+	// current item of a foreach step
+	Variables map[string]interface{}
+
+	mu     sync.RWMutex
+	steps  map[string]models.StepResult
+	parent map[string]interface{}
+}
+
+// NewExecutionContext creates an empty execution context for a workflow run.
+func NewExecutionContext(workflow *models.Workflow, inputs map[string]interface{}) *ExecutionContext {
+	if inputs == nil {
+		inputs = make(map[string]interface{})
+	}
+	metadata := make(map[string]interface{})
+	for k, v := range workflow.Metadata {
+		metadata[k] = v
+	}
+
+	return &ExecutionContext{
+		WorkflowID: workflow.ID,
+		Inputs:     inputs,
+		Metadata:   metadata,
+		steps:      make(map[string]models.StepResult),
+	}
+}
+
+// newChildContext creates the context of a nested execution, such as one
+// iteration of a foreach step. It sees the data of the parent context, given
+// as returned by Data, plus its own steps and variables.
+func newChildContext(parent map[string]interface{}, variables map[string]interface{}) *ExecutionContext {
+	inputs, _ := parent["inputs"].(map[string]interface{})
+	metadata, _ := parent["metadata"].(map[string]interface{})
+
+	return &ExecutionContext{
+		Inputs:    inputs,
+		Metadata:  metadata,
+		Variables: variables,
+		steps:     make(map[string]models.StepResult),
+		parent:    parent,
+	}
+}
+
+// SetStepResult records the result of a completed step.
+func (c *ExecutionContext) SetStepResult(result models.StepResult) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.steps[result.StepID] = result
+}
+
+// StepResult returns the recorded result of a step.
+func (c *ExecutionContext) StepResult(stepID string) (models.StepResult, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	result, exists := c.steps[stepID]
+	return result, exists
+}
+
+// Data returns a snapshot of the context in the form handed to step executors.
+// Step results are available both at the top level, keyed by step ID (e.g.
+// "get-time.data.datetime"), and under "steps". Workflow inputs and metadata
+// are available under "inputs" and "metadata".
+func (c *ExecutionContext) Data() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	steps := make(map[string]interface{}, len(c.steps))
+	data := make(map[string]interface{}, len(c.steps)+len(c.parent)+len(c.Variables)+3)
+	for key, value := range c.parent {
+		data[key] = value
+	}
+	if parentSteps, ok := c.parent["steps"].(map[string]interface{}); ok {
+		for id, entry := range parentSteps {
+			steps[id] = entry
+		}
+	}
+	for key, value := range c.Variables {
+		data[key] = value
+	}
+
+	for id, result := range c.steps {
+		entry := stepResultData(result)
+		steps[id] = entry
+		data[id] = entry
+	}
+
+	data["steps"] = steps
+	data["inputs"] = c.Inputs
+	data["metadata"] = c.Metadata
+	return data
+}
+
+// stepResultData converts a step result into the generic map exposed to steps.
+func stepResultData(result models.StepResult) map[string]interface{} {
+	return map[string]interface{}{
+		"status":   string(result.Status),
+		"data":     result.Data,
+		"error":    result.Error,
+		"attempts": result.Attempts,
+		"metadata": result.Metadata,
+	}
+}
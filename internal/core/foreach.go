@@ -0,0 +1,197 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+	"github.com/tidwall/gjson"
+)
+
+// compositeStep is implemented by step types that run child steps, so that
+// their children are validated together with the workflow.
+type compositeStep interface {
+	childWorkflow() *models.Workflow
+}
+
+// foreachStep runs a child step, or a graph of child steps, once per element of
+// an array found in the execution context. Each iteration sees the parent
+// context plus "item" and "index", and the data of its output step is
+// collected into an array in the same order as the items.
+type foreachStep struct {
+	engine          *WorkflowEngine
+	items           string
+	parallelism     int
+	output          string
+	continueOnError bool
+	children        *models.Workflow
+}
+
+// foreachConfig is the configuration of a foreach step
+type foreachConfig struct {
+	Items           string        `json:"items"`             // JSON path of the array in the execution context
+	Step            *models.Step  `json:"step"`              // Child step run for each item
+	Steps           []models.Step `json:"steps"`             // Graph of child steps run for each item
+	Parallelism     int           `json:"parallelism"`       // Number of items processed at the same time (default 1)
+	Output          string        `json:"output"`            // Child step whose data is collected (default: the last one)
+	ContinueOnError bool          `json:"continue_on_error"` // If true, failed items do not fail the step
+}
+
+// newForEachStep creates a foreach step from its configuration.
+func (w *WorkflowEngine) newForEachStep(config models.StepConfig) (steps.StepExecutor, error) {
+	if err := steps.RequireConfig(config, "items"); err != nil {
+		return nil, err
+	}
+
+	var cfg foreachConfig
+	if err := decodeConfig(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	children := cfg.Steps
+	if cfg.Step != nil {
+		if len(children) > 0 {
+			return nil, fmt.Errorf("step and steps cannot be used together")
+		}
+		child := *cfg.Step
+		if child.ID == "" {
+			child.ID = "step"
+		}
+		child.Required = true
+		children = []models.Step{child}
+	}
+	if len(children) == 0 {
+		return nil, fmt.Errorf("missing required config keys: step or steps")
+	}
+
+	if cfg.Output == "" {
+		cfg.Output = children[len(children)-1].ID
+	}
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = 1
+	}
+
+	return &foreachStep{
+		engine:          w,
+		items:           cfg.Items,
+		parallelism:     cfg.Parallelism,
+		output:          cfg.Output,
+		continueOnError: cfg.ContinueOnError,
+		children:        &models.Workflow{ID: "foreach", Steps: children},
+	}, nil
+}
+
+// childWorkflow implements the compositeStep interface
+func (s *foreachStep) childWorkflow() *models.Workflow {
+	return s.children
+}
+
+// Execute runs the child steps for every item
+func (s *foreachStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	contextJSON, err := json.Marshal(execCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	value := gjson.GetBytes(contextJSON, s.items)
+	if !value.IsArray() {
+		return nil, fmt.Errorf("items path %s does not resolve to an array", s.items)
+	}
+	items := value.Array()
+
+	outputs := make([]interface{}, len(items))
+	var itemErrors []map[string]interface{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.parallelism)
+
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(index int, item interface{}) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			data, err := s.runItem(ctx, execCtx, index, item)
+
+			mu.Lock()
+			defer mu.Unlock()
+			outputs[index] = data
+			if err != nil {
+				itemErrors = append(itemErrors, map[string]interface{}{"index": index, "error": err.Error()})
+			}
+		}(i, item.Value())
+	}
+	wg.Wait()
+
+	sort.Slice(itemErrors, func(i, j int) bool {
+		return itemErrors[i]["index"].(int) < itemErrors[j]["index"].(int)
+	})
+
+	result := &models.StepResult{
+		Status: models.StatusCompleted,
+		Data:   outputs,
+		Metadata: map[string]interface{}{
+			"items":  len(items),
+			"failed": len(itemErrors),
+			"errors": itemErrors,
+		},
+	}
+
+	if err := ctx.Err(); err != nil {
+		return result, err
+	}
+	if len(itemErrors) > 0 && !s.continueOnError {
+		result.Status = models.StatusFailed
+		return result, fmt.Errorf("%d of %d items failed", len(itemErrors), len(items))
+	}
+	return result, nil
+}
+
+// runItem executes the child steps for a single item and returns the data of
+// the output step
+func (s *foreachStep) runItem(ctx context.Context, parent map[string]interface{}, index int, item interface{}) (interface{}, error) {
+	childCtx := newChildContext(parent, map[string]interface{}{
+		"item":  item,
+		"index": index,
+	})
+	result := &models.WorkflowResult{StepResults: make(map[string]models.StepResult)}
+
+	if err := s.engine.executeSteps(ctx, s.children, childCtx, result); err != nil {
+		return nil, err
+	}
+
+	output, exists := childCtx.StepResult(s.output)
+	if !exists || output.Status != models.StatusCompleted {
+		return nil, fmt.Errorf("output step %s did not complete: %s", s.output, output.Error)
+	}
+	return output.Data, nil
+}
+
+// decodeConfig decodes a step configuration into a typed struct
+func decodeConfig(config models.StepConfig, target interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("error encoding config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("error decoding config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// squareStep devolve o quadrado do item atual e falha para números negativos
+type squareStep struct{}
+
+func (s *squareStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	item, ok := execCtx["item"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("item is not a number: %v", execCtx["item"])
+	}
+	if item < 0 {
+		return nil, fmt.Errorf("negative item: %v", item)
+	}
+	return &models.StepResult{Status: models.StatusCompleted, Data: item * item}, nil
+}
+
+// newForEachEngine cria um engine com o tipo de step "square" registrado
+func newForEachEngine(t *testing.T) *WorkflowEngine {
+	engine := NewWorkflowEngine()
+	err := engine.RegisterStepType("square", func(config models.StepConfig) (steps.StepExecutor, error) {
+		return &squareStep{}, nil
+	})
+	if err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+	return engine
+}
+
+// foreachWorkflow monta um workflow com um único step foreach sobre os números informados
+func foreachWorkflow(id string, numbers []interface{}, config map[string]interface{}) *models.Workflow {
+	config["items"] = "metadata.numbers"
+	return &models.Workflow{
+		ID:       id,
+		Name:     id,
+		Metadata: map[string]interface{}{"numbers": numbers},
+		Steps: []models.Step{
+			{ID: "each", Type: "foreach", Config: config, Required: true},
+		},
+	}
+}
+
+func TestForEachSingleStep(t *testing.T) {
+	engine := newForEachEngine(t)
+	workflow := foreachWorkflow("foreach-single", []interface{}{1, 2, 3, 4}, map[string]interface{}{
+		"step":        map[string]interface{}{"type": "square"},
+		"parallelism": 2,
+	})
+
+	result := runGraph(t, engine, workflow)
+
+	each := result.StepResults["each"]
+	expected := []interface{}{1.0, 4.0, 9.0, 16.0}
+	if !reflect.DeepEqual(each.Data, expected) {
+		t.Errorf("Esperava %v, mas obteve %v", expected, each.Data)
+	}
+}
+
+func TestForEachSubGraph(t *testing.T) {
+	engine := newForEachEngine(t)
+	workflow := foreachWorkflow("foreach-graph", []interface{}{2, 3}, map[string]interface{}{
+		"steps": []interface{}{
+			map[string]interface{}{"id": "square", "type": "square", "next": []interface{}{"label"}, "required": true},
+			map[string]interface{}{
+				"id":   "label",
+				"type": "transform",
+				"config": map[string]interface{}{
+					"template": "{{.index}}:{{.value}}",
+					"mapping":  map[string]interface{}{"index": "index", "value": "square.data"},
+				},
+			},
+		},
+	})
+
+	result := runGraph(t, engine, workflow)
+
+	expected := []interface{}{"0:4", "1:9"}
+	if data := result.StepResults["each"].Data; !reflect.DeepEqual(data, expected) {
+		t.Errorf("Esperava %v, mas obteve %v", expected, data)
+	}
+}
+
+func TestForEachItemErrors(t *testing.T) {
+	engine := newForEachEngine(t)
+	numbers := []interface{}{1, -2, 3}
+
+	failing := foreachWorkflow("foreach-fail", numbers, map[string]interface{}{
+		"step": map[string]interface{}{"type": "square"},
+	})
+	if err := engine.RegisterWorkflow(failing); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+	result, err := engine.ExecuteWorkflow(context.Background(), failing.ID)
+	if err == nil {
+		t.Fatal("Esperava erro ao executar workflow com item inválido")
+	}
+	each := result.StepResults["each"]
+	if each.Status != models.StatusFailed {
+		t.Errorf("Esperava status %s, mas obteve %s", models.StatusFailed, each.Status)
+	}
+	itemErrors, _ := each.Metadata["errors"].([]map[string]interface{})
+	if len(itemErrors) != 1 || itemErrors[0]["index"] != 1 {
+		t.Errorf("Esperava erro no item 1, mas obteve %v", each.Metadata["errors"])
+	}
+
+	tolerant := foreachWorkflow("foreach-continue", numbers, map[string]interface{}{
+		"step":              map[string]interface{}{"type": "square"},
+		"continue_on_error": true,
+	})
+	result = runGraph(t, engine, tolerant)
+	expected := []interface{}{1.0, nil, 9.0}
+	if data := result.StepResults["each"].Data; !reflect.DeepEqual(data, expected) {
+		t.Errorf("Esperava %v, mas obteve %v", expected, data)
+	}
+}
+
+func TestForEachValidation(t *testing.T) {
+	engine := newForEachEngine(t)
+	workflow := foreachWorkflow("foreach-invalid", []interface{}{1}, map[string]interface{}{
+		"step": map[string]interface{}{"id": "bad", "type": "unknown"},
+	})
+
+	var validationErrs ValidationErrors
+	if !errors.As(engine.ValidateWorkflow(workflow), &validationErrs) {
+		t.Fatal("Esperava ValidationErrors para step filho inválido")
+	}
+	if validationErrs[0].StepID != "each/bad" || validationErrs[0].Message != "unknown step type: unknown" {
+		t.Errorf("Erro inesperado: %v", validationErrs)
+	}
+}
@@ -0,0 +1,147 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/tidwall/gjson"
+)
+
+// InputError is returned when the inputs of a run do not satisfy the input
+// schema of the workflow.
+type InputError struct {
+	WorkflowID string   `json:"workflow_id"`
+	Problems   []string `json:"errors"`
+}
+
+// Error implements the error interface.
+func (e *InputError) Error() string {
+	return fmt.Sprintf("invalid inputs for workflow %s: %s", e.WorkflowID, strings.Join(e.Problems, "; "))
+}
+
+// schemaKey identifies the input schema of a version of a workflow.
+type schemaKey struct {
+	workflowID string
+	version    int
+}
+
+// compileInputSchema compiles the input schema of a workflow.
+func compileInputSchema(workflow *models.Workflow) (*jsonschema.Schema, error) {
+	data, err := json.Marshal(workflow.InputSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	url := "goflow://workflows/" + workflow.ID + "/inputs.json"
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile(url)
+}
+
+// inputSchema returns the compiled input schema of a workflow, compiling it
+// on first use so that runs do not compile it again. The caller must hold w.mu.
+func (w *WorkflowEngine) inputSchema(workflow *models.Workflow) (*jsonschema.Schema, error) {
+	key := schemaKey{workflowID: workflow.ID, version: workflow.Version}
+	if schema, exists := w.inputSchemas[key]; exists {
+		return schema, nil
+	}
+
+	schema, err := compileInputSchema(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("invalid inputs schema of workflow %s: %w", workflow.ID, err)
+	}
+	w.inputSchemas[key] = schema
+	return schema, nil
+}
+
+// forgetInputSchemas drops the compiled input schemas of every version of a
+// workflow. The caller must hold w.mu.
+func (w *WorkflowEngine) forgetInputSchemas(workflowID string) {
+	for key := range w.inputSchemas {
+		if key.workflowID == workflowID {
+			delete(w.inputSchemas, key)
+		}
+	}
+}
+
+// validateInputs checks the inputs of a run against the input schema of the
+// workflow. Missing inputs are validated as an empty object. The caller must
+// hold w.mu.
+func (w *WorkflowEngine) validateInputs(workflow *models.Workflow, inputs map[string]interface{}) error {
+	if workflow.InputSchema == nil {
+		return nil
+	}
+
+	schema, err := w.inputSchema(workflow)
+	if err != nil {
+		return err
+	}
+
+	// O validador só aceita os tipos produzidos pelo encoding/json
+	if inputs == nil {
+		inputs = make(map[string]interface{})
+	}
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return fmt.Errorf("error encoding inputs: %w", err)
+	}
+	var instance interface{}
+	if err := json.Unmarshal(data, &instance); err != nil {
+		return fmt.Errorf("error decoding inputs: %w", err)
+	}
+
+	err = schema.Validate(instance)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return &InputError{WorkflowID: workflow.ID, Problems: validationProblems(validationErr)}
+	}
+	return err
+}
+
+// validationProblems flattens a schema validation error into one message per
+// failed keyword, prefixed by the location of the offending input.
+func validationProblems(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		location := err.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		return []string{fmt.Sprintf("%s: %s", location, err.Message)}
+	}
+
+	var problems []string
+	for _, cause := range err.Causes {
+		problems = append(problems, validationProblems(cause)...)
+	}
+	return problems
+}
+
+// resolveOutputs evaluates the outputs declared by a workflow against the
+// execution context. Outputs whose path does not resolve are null.
+func resolveOutputs(workflow *models.Workflow, execCtx *ExecutionContext) (map[string]interface{}, error) {
+	if len(workflow.Outputs) == 0 {
+		return nil, nil
+	}
+
+	contextJSON, err := json.Marshal(execCtx.Data())
+	if err != nil {
+		return nil, fmt.Errorf("error encoding execution context: %w", err)
+	}
+
+	outputs := make(map[string]interface{}, len(workflow.Outputs))
+	for name, path := range workflow.Outputs {
+		outputs[name] = gjson.GetBytes(contextJSON, path).Value()
+	}
+	return outputs, nil
+}
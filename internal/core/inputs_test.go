@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+func TestWorkflowInputsAndOutputs(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	workflow := &models.Workflow{
+		ID:   "typed",
+		Name: "Typed",
+		InputSchema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"name"},
+			"properties": map[string]interface{}{
+				"name":  map[string]interface{}{"type": "string"},
+				"count": map[string]interface{}{"type": "integer", "minimum": 1},
+			},
+		},
+		Outputs: map[string]string{
+			"greeting": "greeting.data",
+			"count":    "inputs.count",
+		},
+		Steps: []models.Step{
+			{
+				ID:   "greeting",
+				Type: "transform",
+				Config: map[string]interface{}{
+					"template": "hello {{.name}}",
+					"mapping":  map[string]interface{}{"name": "inputs.name"},
+				},
+			},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	// Inputs inválidos são rejeitados antes de criar a execução
+	_, err := engine.ExecuteWorkflowWithInputs(context.Background(), workflow.ID, map[string]interface{}{"count": 0})
+	var inputErr *InputError
+	if !errors.As(err, &inputErr) {
+		t.Fatalf("Esperava InputError, mas obteve %v", err)
+	}
+	if len(inputErr.Problems) != 2 {
+		t.Errorf("Esperava 2 problemas nos inputs, mas obteve %v", inputErr.Problems)
+	}
+	if _, err := engine.StartWorkflow(workflow.ID, nil); !errors.As(err, &inputErr) {
+		t.Errorf("Esperava InputError ao iniciar sem inputs, mas obteve %v", err)
+	}
+	if runs := engine.ListRuns(workflow.ID); len(runs) != 0 {
+		t.Errorf("Nenhuma execução deveria ter sido criada, mas obteve %d", len(runs))
+	}
+
+	result, err := engine.ExecuteWorkflowWithInputs(context.Background(), workflow.ID, map[string]interface{}{"name": "ana", "count": 2})
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	expected := map[string]interface{}{"greeting": "hello ana", "count": 2.0}
+	if !reflect.DeepEqual(result.Outputs, expected) {
+		t.Errorf("Esperava outputs %v, mas obteve %v", expected, result.Outputs)
+	}
+
+	// O schema é compilado no registro e reaproveitado pelas execuções
+	key := schemaKey{workflowID: workflow.ID, version: workflow.Version}
+	if len(engine.inputSchemas) != 1 || engine.inputSchemas[key] == nil {
+		t.Errorf("Esperava o schema da versão %d em cache: %v", workflow.Version, engine.inputSchemas)
+	}
+	if err := engine.DeleteWorkflow(workflow.ID); err != nil {
+		t.Fatalf("Erro ao remover workflow: %v", err)
+	}
+	if len(engine.inputSchemas) != 0 {
+		t.Errorf("Esperava o cache de schemas vazio após remover o workflow")
+	}
+}
+
+func TestWorkflowInvalidInputSchema(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	workflow := &models.Workflow{
+		ID:          "bad-schema",
+		InputSchema: map[string]interface{}{"type": "not-a-type"},
+		Steps:       []models.Step{{ID: "hello", Type: "echo", Config: map[string]interface{}{"message": "hi"}}},
+	}
+	var validationErrs ValidationErrors
+	if !errors.As(engine.RegisterWorkflow(workflow), &validationErrs) {
+		t.Fatal("Esperava ValidationErrors para schema de inputs inválido")
+	}
+}
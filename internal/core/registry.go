@@ -0,0 +1,102 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// RegisterStepType makes a step type available to the workflows of the engine.
+// The strings of a step config are rendered as templates before each attempt;
+// rawKeys lists the config keys that are handed to the factory as they are,
+// such as keys holding templates of their own.
+func (w *WorkflowEngine) RegisterStepType(name string, factory steps.StepFactory, rawKeys ...string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if name == "" {
+		return fmt.Errorf("step type name cannot be empty")
+	}
+
+	if factory == nil {
+		return fmt.Errorf("step type %s must have a factory", name)
+	}
+
+	if _, exists := w.stepTypes[name]; exists {
+		return fmt.Errorf("step type %s already registered", name)
+	}
+
+	w.stepTypes[name] = factory
+	w.rawConfigKeys[name] = rawKeys
+	return nil
+}
+
+// HasStepType reports whether a step type is registered in the engine.
+func (w *WorkflowEngine) HasStepType(name string) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	_, exists := w.stepTypes[name]
+	return exists
+}
+
+// newStepExecutor creates the executor for a step using the registered factory.
+func (w *WorkflowEngine) newStepExecutor(step models.Step) (steps.StepExecutor, error) {
+	w.mu.RLock()
+	factory, exists := w.stepTypes[step.Type]
+	w.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unknown step type: %s", step.Type)
+	}
+
+	return factory(step.Config)
+}
+
+// registerBuiltinStepTypes registers the step types shipped with GoFlow.
+func (w *WorkflowEngine) registerBuiltinStepTypes() {
+	w.stepTypes["rest"] = func(config models.StepConfig) (steps.StepExecutor, error) {
+		if err := steps.RequireConfig(config, "method", "url"); err != nil {
+			return nil, err
+		}
+		if err := steps.RequireString(config, "method", "url"); err != nil {
+			return nil, err
+		}
+		if err := steps.ValidatePagination(config); err != nil {
+			return nil, err
+		}
+		return steps.NewRestStep(config), nil
+	}
+	w.stringConfigKeys["rest"] = []string{"method", "url"}
+	w.stepTypes["transform"] = func(config models.StepConfig) (steps.StepExecutor, error) {
+		if err := steps.RequireConfig(config, "template", "mapping"); err != nil {
+			return nil, err
+		}
+		if err := steps.RequireString(config, "template"); err != nil {
+			return nil, err
+		}
+		if err := steps.RequireStringMap(config, "mapping"); err != nil {
+			return nil, err
+		}
+		return steps.NewTransformStep(config), nil
+	}
+	w.rawConfigKeys["transform"] = []string{"template"}
+	w.stepTypes["echo"] = func(config models.StepConfig) (steps.StepExecutor, error) {
+		if err := steps.RequireConfig(config, "message"); err != nil {
+			return nil, err
+		}
+		if err := steps.RequireString(config, "message"); err != nil {
+			return nil, err
+		}
+		return steps.NewEchoStep(config), nil
+	}
+	w.stringConfigKeys["echo"] = []string{"message"}
+	w.stepTypes["foreach"] = w.newForEachStep
+	w.rawConfigKeys["foreach"] = []string{"step", "steps"}
+	w.stepTypes["workflow"] = w.newSubWorkflowStep
+}
@@ -0,0 +1,303 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// DefaultMaxRuns is the number of runs kept per workflow when no retention is configured.
+const DefaultMaxRuns = 100
+
+// RunRetention configures how long finished runs are kept in the engine.
+type RunRetention struct {
+	MaxRuns int           // Maximum number of runs kept per workflow (0 means unlimited)
+	MaxAge  time.Duration // Maximum age of a finished run (0 means unlimited)
+}
+
+// StartWorkflow starts a new run of a workflow in the background and returns
+// it immediately. The run is not tied to the caller's context; use CancelRun
+// to stop it.
+func (w *WorkflowEngine) StartWorkflow(workflowID string, inputs map[string]interface{}) (*models.Run, error) {
+	w.mu.Lock()
+	workflow, exists := w.workflows[workflowID]
+	if !exists {
+		w.mu.Unlock()
+		return nil, fmt.Errorf("workflow %s not found", workflowID)
+	}
+
+	run, err := w.createRun(workflow, inputs, "")
+	if err != nil {
+		w.mu.Unlock()
+		return nil, err
+	}
+	w.startRun(workflow, run)
+	snapshot := copyRun(run)
+	w.mu.Unlock()
+
+	return snapshot, nil
+}
+
+// startRun executes a run in the background. The caller must hold w.mu.
+func (w *WorkflowEngine) startRun(workflow *models.Workflow, run *models.Run) {
+	ctx, cancel := w.cancellable(context.Background(), run)
+
+	go func() {
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("run %s panicked: %v", run.ID, r)
+
+				w.mu.Lock()
+				defer w.mu.Unlock()
+				if run.Status == models.StatusRunning {
+					w.finishRun(run, models.StatusFailed, fmt.Errorf("run panicked: %v", r))
+				}
+			}
+		}()
+		w.executeRun(ctx, workflow, run)
+	}()
+}
+
+// cancellable returns the context a run executes with, cancelled by CancelRun
+// or by the returned function. The caller must hold w.mu.
+func (w *WorkflowEngine) cancellable(ctx context.Context, run *models.Run) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	w.cancels[run.ID] = cancel
+	return ctx, cancel
+}
+
+// ResumeRuns resumes, in the background, the runs persisted as running that
+// are not being executed by this engine, such as those interrupted by a crash.
+// Steps whose result was checkpointed are not executed again. Runs whose
+// workflow was deleted or re-registered since they started are marked as failed.
+func (w *WorkflowEngine) ResumeRuns() []*models.Run {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var resumed []*models.Run
+	for _, run := range w.runs {
+		if _, active := w.cancels[run.ID]; active || run.Status != models.StatusRunning {
+			continue
+		}
+
+		// Execuções de sub-workflows são refeitas pela execução que as invocou
+		if run.ParentRunID != "" {
+			err := fmt.Errorf("run %s was interrupted; its parent run %s starts a new one", run.ID, run.ParentRunID)
+			w.finishRun(run, models.StatusFailed, err)
+			continue
+		}
+
+		workflow, exists := w.workflows[run.WorkflowID]
+		if !exists || workflow.Version != run.WorkflowVersion {
+			err := fmt.Errorf("cannot resume run %s: workflow %s version %d is no longer registered",
+				run.ID, run.WorkflowID, run.WorkflowVersion)
+			w.finishRun(run, models.StatusFailed, err)
+			continue
+		}
+
+		run.UpdatedAt = time.Now()
+		w.startRun(workflow, run)
+		resumed = append(resumed, copyRun(run))
+	}
+	return resumed
+}
+
+// CancelRun cancels a running run. Steps that have not finished yet are
+// marked as cancelled.
+func (w *WorkflowEngine) CancelRun(runID string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run, exists := w.runs[runID]
+	if !exists {
+		return fmt.Errorf("run %s not found", runID)
+	}
+
+	cancel, running := w.cancels[runID]
+	if !running || run.Status != models.StatusRunning {
+		return fmt.Errorf("run %s is not running", runID)
+	}
+
+	cancel()
+	return nil
+}
+
+// GetRun returns a snapshot of a run by its ID.
+func (w *WorkflowEngine) GetRun(runID string) (*models.Run, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	run, exists := w.runs[runID]
+	if !exists {
+		return nil, false
+	}
+	return copyRun(run), true
+}
+
+// ListRuns returns snapshots of the runs of a workflow, most recent first.
+func (w *WorkflowEngine) ListRuns(workflowID string) []*models.Run {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	var runs []*models.Run
+	for _, run := range w.runs {
+		if run.WorkflowID == workflowID {
+			runs = append(runs, copyRun(run))
+		}
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+	return runs
+}
+
+// createRun creates and stores a new run of a workflow after validating its
+// inputs. parentRunID links the run to the run that invoked it, if any. The
+// caller must hold w.mu.
+func (w *WorkflowEngine) createRun(workflow *models.Workflow, inputs map[string]interface{}, parentRunID string) (*models.Run, error) {
+	if err := w.validateInputs(workflow, inputs); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	run := &models.Run{
+		ID:              newRunID(),
+		WorkflowID:      workflow.ID,
+		WorkflowVersion: workflow.Version,
+		ParentRunID:     parentRunID,
+		Inputs:          inputs,
+		Status:          models.StatusRunning,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	run.Result = &models.WorkflowResult{
+		WorkflowID:  workflow.ID,
+		RunID:       run.ID,
+		Status:      models.StatusRunning,
+		StepResults: make(map[string]models.StepResult),
+		StartTime:   now,
+	}
+
+	if err := w.store.SaveRun(run); err != nil {
+		return nil, fmt.Errorf("error saving run: %w", err)
+	}
+
+	w.runs[run.ID] = run
+	return run, nil
+}
+
+// checkpointRun persists the current state of a run so that it can be resumed
+// after a crash.
+func (w *WorkflowEngine) checkpointRun(run *models.Run) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run.UpdatedAt = time.Now()
+	if err := w.store.SaveRun(run); err != nil {
+		log.Printf("error checkpointing run %s: %v", run.ID, err)
+	}
+}
+
+// runStatus returns the final status of a run from its context and error.
+func runStatus(ctx context.Context, err error) models.WorkflowStatus {
+	switch {
+	case err == nil:
+		return models.StatusCompleted
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return models.StatusTimedOut
+	case errors.Is(ctx.Err(), context.Canceled):
+		return models.StatusCancelled
+	default:
+		return models.StatusFailed
+	}
+}
+
+// finishRun records the outcome of a run. The caller must hold w.mu.
+func (w *WorkflowEngine) finishRun(run *models.Run, status models.WorkflowStatus, err error) {
+	result := run.Result
+	result.EndTime = time.Now()
+	result.Status = status
+	if err != nil {
+		result.Error = err.Error()
+	}
+	run.Status = result.Status
+	run.UpdatedAt = result.EndTime
+	delete(w.cancels, run.ID)
+
+	if err := w.store.SaveRun(run); err != nil {
+		log.Printf("error saving run %s: %v", run.ID, err)
+	}
+	w.pruneRuns(run.WorkflowID, result.EndTime)
+}
+
+// pruneRuns removes the finished runs of a workflow that fall outside the
+// retention policy. The caller must hold w.mu.
+func (w *WorkflowEngine) pruneRuns(workflowID string, now time.Time) {
+	var finished []*models.Run
+	for _, run := range w.runs {
+		if run.WorkflowID != workflowID || run.Status == models.StatusRunning {
+			continue
+		}
+		if w.retention.MaxAge > 0 && now.Sub(run.UpdatedAt) > w.retention.MaxAge {
+			w.deleteRun(run.ID)
+			continue
+		}
+		finished = append(finished, run)
+	}
+
+	if w.retention.MaxRuns <= 0 || len(finished) <= w.retention.MaxRuns {
+		return
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.After(finished[j].CreatedAt)
+	})
+	for _, run := range finished[w.retention.MaxRuns:] {
+		w.deleteRun(run.ID)
+	}
+}
+
+// deleteRun removes a run from the engine and the store. The caller must hold w.mu.
+func (w *WorkflowEngine) deleteRun(runID string) {
+	delete(w.runs, runID)
+	if err := w.store.DeleteRun(runID); err != nil {
+		log.Printf("error deleting run %s: %v", runID, err)
+	}
+}
+
+// copyRun returns a copy of a run that is safe to use after w.mu is released.
+func copyRun(run *models.Run) *models.Run {
+	runCopy := *run
+	if run.Result != nil {
+		result := *run.Result
+		result.StepResults = make(map[string]models.StepResult, len(run.Result.StepResults))
+		for id, stepResult := range run.Result.StepResults {
+			result.StepResults[id] = stepResult
+		}
+		result.Compensations = append([]models.CompensationResult(nil), run.Result.Compensations...)
+		runCopy.Result = &result
+	}
+	return &runCopy
+}
+
+// newRunID generates a random identifier for a run.
+func newRunID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,329 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/expression"
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// stepGraph is the dependency graph of a workflow. Every "next" and "on_error"
+// reference is an edge; a step becomes ready once all of its incoming edges are
+// resolved and at least one of them was activated. Steps whose edges were all
+// resolved without activation are skipped.
+type stepGraph struct {
+	steps    map[string]models.Step
+	order    []string
+	inDegree map[string]int
+}
+
+// newStepGraph builds the dependency graph of a workflow. Compensation steps
+// are not part of the graph; they only run when the workflow fails.
+func newStepGraph(workflow *models.Workflow) *stepGraph {
+	graph := &stepGraph{
+		steps:    make(map[string]models.Step, len(workflow.Steps)),
+		order:    make([]string, 0, len(workflow.Steps)),
+		inDegree: make(map[string]int, len(workflow.Steps)),
+	}
+
+	compensations := compensationSteps(workflow)
+	for _, step := range workflow.Steps {
+		if compensations[step.ID] {
+			continue
+		}
+		graph.steps[step.ID] = step
+		graph.order = append(graph.order, step.ID)
+	}
+	for _, step := range graph.steps {
+		for _, next := range successors(step) {
+			graph.inDegree[next]++
+		}
+	}
+	return graph
+}
+
+// initialSteps returns the steps without incoming edges, in definition order.
+func (g *stepGraph) initialSteps() []string {
+	var initial []string
+	for _, id := range g.order {
+		if g.inDegree[id] == 0 {
+			initial = append(initial, id)
+		}
+	}
+	return initial
+}
+
+// stepOutcome is reported by a worker when a step finishes.
+type stepOutcome struct {
+	step   models.Step
+	result models.StepResult
+	err    error
+}
+
+// stepScheduler runs the steps of a workflow in topological order, running
+// independent branches in parallel up to the workflow's max concurrency.
+type stepScheduler struct {
+	engine         *WorkflowEngine
+	graph          *stepGraph
+	execCtx        *ExecutionContext
+	result         *models.WorkflowResult
+	maxConcurrency int
+
+	// checkpoint, when set, is called after each step result is recorded
+	checkpoint func()
+
+	pending   map[string]int
+	activated map[string]bool
+	bypassed  map[string]bool
+	restored  map[string]models.StepResult
+
+	// conditionErrs holds the errors of the conditions evaluated for each step
+	conditionErrs map[string][]string
+	ready         []string
+	firstErr      error
+
+	// completed lists the steps that completed successfully, in completion order
+	completed []string
+}
+
+// newStepScheduler creates the scheduler of a workflow run. Steps that already
+// have a final result in result.StepResults, such as those restored from a
+// checkpoint, are not executed again.
+func (w *WorkflowEngine) newStepScheduler(workflow *models.Workflow, execCtx *ExecutionContext, result *models.WorkflowResult) *stepScheduler {
+	graph := newStepGraph(workflow)
+	scheduler := &stepScheduler{
+		engine:         w,
+		graph:          graph,
+		execCtx:        execCtx,
+		result:         result,
+		maxConcurrency: workflow.MaxConcurrency,
+		pending:        make(map[string]int, len(graph.inDegree)),
+		activated:      make(map[string]bool),
+		bypassed:       make(map[string]bool),
+		restored:       make(map[string]models.StepResult),
+		conditionErrs:  make(map[string][]string),
+		ready:          graph.initialSteps(),
+	}
+	for id, degree := range graph.inDegree {
+		scheduler.pending[id] = degree
+	}
+
+	w.mu.Lock()
+	for id, stepResult := range result.StepResults {
+		switch stepResult.Status {
+		case models.StatusCompleted, models.StatusFailed:
+			scheduler.restored[id] = stepResult
+		default:
+			delete(result.StepResults, id)
+		}
+	}
+	w.mu.Unlock()
+
+	return scheduler
+}
+
+// executeSteps executes the workflow steps.
+func (w *WorkflowEngine) executeSteps(ctx context.Context, workflow *models.Workflow, execCtx *ExecutionContext, result *models.WorkflowResult) error {
+	return w.newStepScheduler(workflow, execCtx, result).run(ctx)
+}
+
+// run schedules the ready steps until the graph is exhausted. A failed
+// required step stops the scheduling of new steps and fails the workflow.
+func (s *stepScheduler) run(ctx context.Context) error {
+	outcomes := make(chan stepOutcome)
+	running := 0
+
+	for {
+		for len(s.ready) > 0 && s.firstErr == nil && ctx.Err() == nil &&
+			(s.maxConcurrency <= 0 || running < s.maxConcurrency) {
+			step := s.graph.steps[s.ready[0]]
+			s.ready = s.ready[1:]
+
+			// Steps restaurados de um checkpoint não são executados novamente
+			if restored, ok := s.restored[step.ID]; ok {
+				var err error
+				if restored.Status != models.StatusCompleted {
+					err = errors.New(restored.Error)
+				}
+				s.handleOutcome(ctx, stepOutcome{step: step, result: restored, err: err})
+				continue
+			}
+
+			running++
+			go func(step models.Step) {
+				result, err := s.engine.executeStep(ctx, step, s.execCtx)
+				outcomes <- stepOutcome{step: step, result: result, err: err}
+			}(step)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		outcome := <-outcomes
+		running--
+
+		s.engine.mu.Lock()
+		s.result.StepResults[outcome.step.ID] = outcome.result
+		s.engine.mu.Unlock()
+		if s.checkpoint != nil {
+			s.checkpoint()
+		}
+
+		s.handleOutcome(ctx, outcome)
+	}
+
+	if s.firstErr != nil {
+		return s.firstErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		s.cancelPendingSteps()
+		return err
+	}
+	return nil
+}
+
+// handleOutcome makes the result of a finished step available to the next
+// steps and resolves its outgoing edges.
+func (s *stepScheduler) handleOutcome(ctx context.Context, outcome stepOutcome) {
+	s.execCtx.SetStepResult(outcome.result)
+	if outcome.err == nil {
+		s.completed = append(s.completed, outcome.step.ID)
+	}
+
+	if outcome.err != nil && outcome.step.Required && s.firstErr == nil {
+		s.firstErr = fmt.Errorf("error in step %s: %w", outcome.step.ID, outcome.err)
+	}
+
+	// Com o contexto encerrado os dependentes ficam pendentes e serão cancelados
+	if ctx.Err() != nil {
+		return
+	}
+
+	// Resolve as arestas de saída: "next" em caso de sucesso, se a condição for
+	// satisfeita, e "on_error" em caso de falha
+	succeeded := outcome.err == nil
+	var data map[string]interface{}
+	for _, next := range outcome.step.Next {
+		taken := succeeded
+		if taken && next.When != "" {
+			if data == nil {
+				data = s.execCtx.Data()
+			}
+			var err error
+			if taken, err = expression.EvaluateBool(next.When, data); err != nil {
+				s.conditionErrs[next.Step] = append(s.conditionErrs[next.Step], err.Error())
+			}
+		}
+		s.resolve(next.Step, taken)
+	}
+	for _, onError := range outcome.step.OnError {
+		s.resolve(onError, !succeeded)
+	}
+}
+
+// cancelPendingSteps marks the steps that did not get to run as cancelled.
+func (s *stepScheduler) cancelPendingSteps() {
+	now := time.Now()
+
+	s.engine.mu.Lock()
+	defer s.engine.mu.Unlock()
+
+	for _, id := range s.graph.order {
+		if _, done := s.result.StepResults[id]; done || s.bypassed[id] {
+			continue
+		}
+		s.result.StepResults[id] = models.StepResult{
+			StepID:    id,
+			Status:    models.StatusCancelled,
+			StartTime: now,
+			EndTime:   now,
+		}
+	}
+}
+
+// resolve resolves one incoming edge of a step. Once all edges are resolved the
+// step is either queued, if any edge was activated, or bypassed together with
+// the steps that depend only on it.
+func (s *stepScheduler) resolve(stepID string, activate bool) {
+	if activate {
+		s.activated[stepID] = true
+	}
+
+	s.pending[stepID]--
+	if s.pending[stepID] > 0 {
+		return
+	}
+
+	if s.activated[stepID] {
+		s.ready = append(s.ready, stepID)
+		return
+	}
+
+	s.skip(stepID)
+	step := s.graph.steps[stepID]
+	for _, next := range successors(step) {
+		s.resolve(next, false)
+	}
+}
+
+// skip marks a step that no transition selected as skipped.
+func (s *stepScheduler) skip(stepID string) {
+	now := time.Now()
+	stepResult := models.StepResult{
+		StepID:    stepID,
+		Status:    models.StatusSkipped,
+		StartTime: now,
+		EndTime:   now,
+		Error:     strings.Join(s.conditionErrs[stepID], "; "),
+	}
+
+	s.bypassed[stepID] = true
+	s.engine.mu.Lock()
+	s.result.StepResults[stepID] = stepResult
+	s.engine.mu.Unlock()
+	s.execCtx.SetStepResult(stepResult)
+}
+
+// executeStep executes a single step and returns its result.
+func (w *WorkflowEngine) executeStep(ctx context.Context, step models.Step, execCtx *ExecutionContext) (models.StepResult, error) {
+	stepResult := models.StepResult{
+		StepID:    step.ID,
+		Status:    models.StatusRunning,
+		StartTime: time.Now(),
+		Attempts:  0,
+	}
+
+	var err error
+	if step.Retry != nil {
+		err = w.executeWithRetry(ctx, step, execCtx, &stepResult)
+	} else {
+		err = w.executeSingleStep(ctx, step, execCtx, &stepResult)
+	}
+
+	stepResult.EndTime = time.Now()
+	if err != nil {
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			stepResult.Status = models.StatusTimedOut
+		case errors.Is(ctx.Err(), context.Canceled):
+			stepResult.Status = models.StatusCancelled
+		default:
+			stepResult.Status = models.StatusFailed
+		}
+		stepResult.Error = err.Error()
+	} else {
+		stepResult.Status = models.StatusCompleted
+	}
+
+	return stepResult, err
+}
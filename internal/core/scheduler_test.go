@@ -0,0 +1,279 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// probe registra a ordem de execução e a concorrência dos steps de teste
+type probe struct {
+	mu       sync.Mutex
+	started  []string
+	finished map[string]bool
+	running  int
+	peak     int
+	barrier  chan struct{}
+	parties  int
+	arrived  int
+}
+
+func newProbe() *probe {
+	return &probe{finished: make(map[string]bool)}
+}
+
+// withBarrier faz os steps marcados com "wait" esperarem até que n deles estejam
+// rodando ao mesmo tempo
+func (p *probe) withBarrier(n int) *probe {
+	p.barrier = make(chan struct{})
+	p.parties = n
+	return p
+}
+
+type probeStep struct {
+	probe *probe
+	id    string
+	deps  []string
+	wait  bool
+}
+
+func (s *probeStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	p := s.probe
+
+	p.mu.Lock()
+	for _, dep := range s.deps {
+		if !p.finished[dep] {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("step %s started before dependency %s", s.id, dep)
+		}
+	}
+	p.started = append(p.started, s.id)
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	var barrier chan struct{}
+	if s.wait {
+		barrier = p.barrier
+		p.arrived++
+		if p.arrived == p.parties {
+			close(barrier)
+		}
+	}
+	p.mu.Unlock()
+
+	var err error
+	if barrier != nil {
+		select {
+		case <-barrier:
+		case <-time.After(2 * time.Second):
+			err = fmt.Errorf("step %s did not run in parallel", s.id)
+		}
+	}
+
+	p.mu.Lock()
+	p.running--
+	p.finished[s.id] = true
+	p.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return &models.StepResult{Status: models.StatusCompleted, Data: s.id}, nil
+}
+
+// newProbeEngine cria um engine com o tipo de step "probe" registrado
+func newProbeEngine(t *testing.T, p *probe) *WorkflowEngine {
+	engine := NewWorkflowEngine()
+	err := engine.RegisterStepType("probe", func(config models.StepConfig) (steps.StepExecutor, error) {
+		deps, _ := config["deps"].([]string)
+		wait, _ := config["wait"].(bool)
+		return &probeStep{probe: p, id: config["id"].(string), deps: deps, wait: wait}, nil
+	})
+	if err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+	return engine
+}
+
+// graphWorkflow monta um workflow de steps "probe" a partir das arestas informadas
+func graphWorkflow(id string, maxConcurrency int, order []string, edges map[string][]string) *models.Workflow {
+	deps := make(map[string][]string)
+	for from, targets := range edges {
+		for _, to := range targets {
+			deps[to] = append(deps[to], from)
+		}
+	}
+
+	workflow := &models.Workflow{ID: id, Name: id, MaxConcurrency: maxConcurrency}
+	for _, stepID := range order {
+		var next []models.Transition
+		for _, to := range edges[stepID] {
+			next = append(next, models.Transition{Step: to})
+		}
+		workflow.Steps = append(workflow.Steps, models.Step{
+			ID:       stepID,
+			Type:     "probe",
+			Config:   map[string]interface{}{"id": stepID, "deps": deps[stepID]},
+			Next:     next,
+			Required: true,
+		})
+	}
+	return workflow
+}
+
+func runGraph(t *testing.T, engine *WorkflowEngine, workflow *models.Workflow) *models.WorkflowResult {
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+	return result
+}
+
+func TestSchedulerDiamond(t *testing.T) {
+	p := newProbe()
+	engine := newProbeEngine(t, p)
+
+	workflow := graphWorkflow("diamond", 1, []string{"a", "b", "c", "d"}, map[string][]string{
+		"a": {"b", "c"},
+		"b": {"d"},
+		"c": {"d"},
+	})
+	result := runGraph(t, engine, workflow)
+
+	// Com concorrência 1 a ordem é determinística e cada step roda uma única vez
+	expected := []string{"a", "b", "c", "d"}
+	if !reflect.DeepEqual(p.started, expected) {
+		t.Errorf("Ordem esperada %v, mas obteve %v", expected, p.started)
+	}
+
+	if len(result.StepResults) != 4 {
+		t.Errorf("Esperava 4 resultados de steps, mas obteve %d", len(result.StepResults))
+	}
+}
+
+func TestSchedulerFanOut(t *testing.T) {
+	p := newProbe().withBarrier(3)
+	engine := newProbeEngine(t, p)
+
+	workflow := graphWorkflow("fan-out", 0, []string{"root", "b1", "b2", "b3"}, map[string][]string{
+		"root": {"b1", "b2", "b3"},
+	})
+
+	// Os três ramos só passam da barreira se rodarem em paralelo
+	for i := 1; i < len(workflow.Steps); i++ {
+		workflow.Steps[i].Config["wait"] = true
+	}
+
+	result := runGraph(t, engine, workflow)
+	if result.Status != models.StatusCompleted {
+		t.Errorf("Status esperado %s, mas obteve %s: %s", models.StatusCompleted, result.Status, result.Error)
+	}
+
+	if p.started[0] != "root" || len(p.started) != 4 {
+		t.Errorf("Esperava root seguido dos três ramos, mas obteve %v", p.started)
+	}
+}
+
+func TestSchedulerFanIn(t *testing.T) {
+	p := newProbe()
+	engine := newProbeEngine(t, p)
+
+	workflow := graphWorkflow("fan-in", 2, []string{"s1", "s2", "s3", "s4", "join"}, map[string][]string{
+		"s1": {"join"},
+		"s2": {"join"},
+		"s3": {"join"},
+		"s4": {"join"},
+	})
+	result := runGraph(t, engine, workflow)
+
+	if len(p.started) != 5 || p.started[4] != "join" {
+		t.Errorf("Esperava join por último e uma única vez, mas obteve %v", p.started)
+	}
+
+	if p.peak > 2 {
+		t.Errorf("Esperava no máximo 2 steps simultâneos, mas obteve %d", p.peak)
+	}
+
+	if result.StepResults["join"].Status != models.StatusCompleted {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusCompleted, result.StepResults["join"].Status)
+	}
+}
+
+func TestSchedulerOnError(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	// Um step opcional que falha aciona apenas os steps de on_error
+	workflow := &models.Workflow{
+		ID:   "on-error",
+		Name: "On Error",
+		Steps: []models.Step{
+			{ID: "call", Type: "rest", Config: map[string]interface{}{"method": "GET", "url": "http://127.0.0.1:0"}, Next: []models.Transition{{Step: "done"}}, OnError: []string{"recover"}},
+			{ID: "done", Type: "echo", Config: map[string]interface{}{"message": "done"}},
+			{ID: "recover", Type: "echo", Config: map[string]interface{}{"message": "recovered"}},
+		},
+	}
+	result := runGraph(t, engine, workflow)
+
+	if result.StepResults["call"].Status != models.StatusFailed {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusFailed, result.StepResults["call"].Status)
+	}
+
+	if status := result.StepResults["done"].Status; status != models.StatusSkipped {
+		t.Errorf("Status esperado do step done %s, mas obteve %s", models.StatusSkipped, status)
+	}
+
+	if result.StepResults["recover"].Data != "recovered" {
+		t.Errorf("Esperava que o step recover fosse executado, resultados: %v", result.StepResults)
+	}
+}
+
+func TestSchedulerConditionalBranches(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	definition := `{
+		"id": "conditional",
+		"name": "Conditional",
+		"steps": [
+			{"id": "check", "type": "echo", "config": {"message": "down"},
+			 "next": [
+				{"step": "notify", "when": "steps.check.data == 'down'"},
+				{"step": "archive", "when": "steps.check.data == 'up'"}
+			 ]},
+			{"id": "notify", "type": "echo", "config": {"message": "notified"}, "next": ["report"]},
+			{"id": "archive", "type": "echo", "config": {"message": "archived"}, "next": ["cleanup", "report"]},
+			{"id": "cleanup", "type": "echo", "config": {"message": "cleaned"}},
+			{"id": "report", "type": "echo", "config": {"message": "reported"}}
+		]
+	}`
+
+	var workflow models.Workflow
+	if err := json.Unmarshal([]byte(definition), &workflow); err != nil {
+		t.Fatalf("Erro ao decodificar workflow: %v", err)
+	}
+	result := runGraph(t, engine, &workflow)
+
+	expected := map[string]models.WorkflowStatus{
+		"check":   models.StatusCompleted,
+		"notify":  models.StatusCompleted,
+		"archive": models.StatusSkipped,
+		"cleanup": models.StatusSkipped,
+		"report":  models.StatusCompleted,
+	}
+	for stepID, status := range expected {
+		if result.StepResults[stepID].Status != status {
+			t.Errorf("Status esperado do step %s %s, mas obteve %s", stepID, status, result.StepResults[stepID].Status)
+		}
+	}
+}
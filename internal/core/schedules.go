@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/carloskvasir/goflow/internal/schedule"
+)
+
+// StartScheduler starts firing the schedules of the registered workflows.
+func (w *WorkflowEngine) StartScheduler() {
+	w.schedules.Start()
+}
+
+// GetSchedule returns the state of the schedule of a workflow and its next
+// upcoming fire times.
+func (w *WorkflowEngine) GetSchedule(workflowID string, upcoming int) (*schedule.Status, bool) {
+	return w.schedules.Status(workflowID, upcoming)
+}
+
+// ListSchedules returns the state of the schedules of every workflow.
+func (w *WorkflowEngine) ListSchedules() []*schedule.Status {
+	return w.schedules.List()
+}
+
+// PauseSchedule stops running a workflow on its schedule until it is resumed.
+// The paused state is persisted with the workflow.
+func (w *WorkflowEngine) PauseSchedule(workflowID string) error {
+	return w.setSchedulePaused(workflowID, true)
+}
+
+// ResumeSchedule resumes the schedule of a workflow paused by PauseSchedule.
+func (w *WorkflowEngine) ResumeSchedule(workflowID string) error {
+	return w.setSchedulePaused(workflowID, false)
+}
+
+// setSchedulePaused pauses or resumes the schedule of a workflow.
+func (w *WorkflowEngine) setSchedulePaused(workflowID string, paused bool) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	workflow, exists := w.workflows[workflowID]
+	if !exists {
+		return fmt.Errorf("workflow %s not found", workflowID)
+	}
+	if workflow.Schedule == nil {
+		return fmt.Errorf("workflow %s has no schedule", workflowID)
+	}
+
+	// Runs and API responses may be reading the registered workflow, so the
+	// change goes to a copy that replaces it once persisted
+	updated := *workflow
+	updatedSchedule := *workflow.Schedule
+	updatedSchedule.Paused = paused
+	updated.Schedule = &updatedSchedule
+	if err := w.store.SaveWorkflow(&updated); err != nil {
+		return fmt.Errorf("error saving workflow %s: %w", workflowID, err)
+	}
+	w.workflows[workflowID] = &updated
+
+	if paused {
+		return w.schedules.Pause(workflowID)
+	}
+	return w.schedules.Resume(workflowID)
+}
@@ -0,0 +1,149 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// runFrame identifies a run in the chain of workflows invoked by a run.
+type runFrame struct {
+	workflowID string
+	runID      string
+}
+
+// runStackKey is the context key of the chain of runs being executed.
+type runStackKey struct{}
+
+// withRunFrame returns a context that records the run as the innermost one of
+// the chain of runs being executed.
+func withRunFrame(ctx context.Context, workflowID, runID string) context.Context {
+	stack := runStack(ctx)
+	frames := make([]runFrame, len(stack), len(stack)+1)
+	copy(frames, stack)
+	return context.WithValue(ctx, runStackKey{}, append(frames, runFrame{workflowID: workflowID, runID: runID}))
+}
+
+// runStack returns the chain of runs being executed, outermost first.
+func runStack(ctx context.Context) []runFrame {
+	stack, _ := ctx.Value(runStackKey{}).([]runFrame)
+	return stack
+}
+
+// subWorkflowStep runs another registered workflow as a child run and waits for
+// it to finish. The outputs of the child run become the data of the step.
+type subWorkflowStep struct {
+	engine     *WorkflowEngine
+	workflowID string
+	inputs     map[string]interface{}
+}
+
+// newSubWorkflowStep creates a workflow step from its configuration. The
+// "inputs" map gives the inputs of the child run; like the rest of the config,
+// its strings are rendered as templates, so that {{ steps.user.data }} passes
+// the data of a step and other strings are passed as they are.
+func (w *WorkflowEngine) newSubWorkflowStep(config models.StepConfig) (steps.StepExecutor, error) {
+	if err := steps.RequireConfig(config, "workflow"); err != nil {
+		return nil, err
+	}
+
+	workflowID, ok := config["workflow"].(string)
+	if !ok || workflowID == "" {
+		return nil, fmt.Errorf("workflow must be a non-empty string")
+	}
+
+	var inputs map[string]interface{}
+	if raw, exists := config["inputs"]; exists {
+		if inputs, ok = raw.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("inputs must be an object")
+		}
+	}
+
+	return &subWorkflowStep{engine: w, workflowID: workflowID, inputs: inputs}, nil
+}
+
+// Execute runs the child workflow
+func (s *subWorkflowStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	stack := runStack(ctx)
+	for i, frame := range stack {
+		if frame.workflowID == s.workflowID {
+			chain := make([]string, 0, len(stack)-i+1)
+			for _, f := range stack[i:] {
+				chain = append(chain, f.workflowID)
+			}
+			chain = append(chain, s.workflowID)
+			return nil, fmt.Errorf("recursive workflow invocation: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	var parentRunID string
+	if len(stack) > 0 {
+		parentRunID = stack[len(stack)-1].runID
+	}
+
+	result, err := s.engine.executeChildWorkflow(ctx, s.workflowID, s.inputs, parentRunID)
+	if result == nil {
+		return nil, err
+	}
+
+	stepResult := &models.StepResult{
+		Status: models.StatusCompleted,
+		Data:   workflowOutputs(result),
+		Metadata: map[string]interface{}{
+			"workflow_id": result.WorkflowID,
+			"run_id":      result.RunID,
+			"status":      string(result.Status),
+		},
+	}
+	if err != nil {
+		stepResult.Status = models.StatusFailed
+		return stepResult, fmt.Errorf("workflow %s failed: %w", s.workflowID, err)
+	}
+	return stepResult, nil
+}
+
+// executeChildWorkflow executes a workflow as a child of another run and waits
+// for it to finish. The child run is cancelled together with ctx.
+func (w *WorkflowEngine) executeChildWorkflow(ctx context.Context, workflowID string, inputs map[string]interface{}, parentRunID string) (*models.WorkflowResult, error) {
+	w.mu.Lock()
+	workflow, exists := w.workflows[workflowID]
+	if !exists {
+		w.mu.Unlock()
+		return nil, fmt.Errorf("workflow %s not found", workflowID)
+	}
+
+	run, err := w.createRun(workflow, inputs, parentRunID)
+	if err != nil {
+		w.mu.Unlock()
+		return nil, err
+	}
+	ctx, cancel := w.cancellable(ctx, run)
+	w.mu.Unlock()
+	defer cancel()
+
+	return w.executeRun(ctx, workflow, run)
+}
+
+// workflowOutputs returns the outputs of a run: the outputs declared by the
+// workflow or, if it declares none, the data of each completed step, keyed by
+// step ID.
+func workflowOutputs(result *models.WorkflowResult) map[string]interface{} {
+	if result.Outputs != nil {
+		return result.Outputs
+	}
+
+	outputs := make(map[string]interface{}, len(result.StepResults))
+	for id, stepResult := range result.StepResults {
+		if stepResult.Status == models.StatusCompleted {
+			outputs[id] = stepResult.Data
+		}
+	}
+	return outputs
+}
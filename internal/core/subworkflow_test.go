@@ -0,0 +1,100 @@
+package core
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// callStep monta um step que invoca outro workflow
+func callStep(id, workflowID string, inputs map[string]interface{}) models.Step {
+	return models.Step{
+		ID:       id,
+		Type:     "workflow",
+		Config:   map[string]interface{}{"workflow": workflowID, "inputs": inputs},
+		Required: true,
+	}
+}
+
+func TestSubWorkflow(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	child := &models.Workflow{
+		ID:   "greet",
+		Name: "Greet",
+		Steps: []models.Step{
+			{
+				ID:   "greeting",
+				Type: "transform",
+				Config: map[string]interface{}{
+					"template": "{{.greeting}} {{.name}}",
+					"mapping":  map[string]interface{}{"name": "inputs.name", "greeting": "inputs.greeting"},
+				},
+			},
+		},
+	}
+	parent := &models.Workflow{
+		ID:   "parent",
+		Name: "Parent",
+		Steps: []models.Step{
+			{ID: "user", Type: "echo", Config: map[string]interface{}{"message": "ana"}, Next: []models.Transition{{Step: "call"}}},
+			callStep("call", "greet", map[string]interface{}{"name": "{{ steps.user.data }}", "greeting": "hello"}),
+		},
+	}
+	for _, workflow := range []*models.Workflow{child, parent} {
+		if err := engine.RegisterWorkflow(workflow); err != nil {
+			t.Fatalf("Erro ao registrar workflow: %v", err)
+		}
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), parent.ID)
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	call := result.StepResults["call"]
+	expected := map[string]interface{}{"greeting": "hello ana"}
+	if !reflect.DeepEqual(call.Data, expected) {
+		t.Errorf("Esperava %v, mas obteve %v", expected, call.Data)
+	}
+
+	// A execução filha deve estar ligada à execução que a invocou
+	childRuns := engine.ListRuns(child.ID)
+	if len(childRuns) != 1 {
+		t.Fatalf("Esperava 1 execução do workflow filho, mas obteve %d", len(childRuns))
+	}
+	if childRuns[0].ParentRunID != result.RunID {
+		t.Errorf("Esperava execução pai %s, mas obteve %s", result.RunID, childRuns[0].ParentRunID)
+	}
+	if call.Metadata["run_id"] != childRuns[0].ID {
+		t.Errorf("Esperava run_id %s nos metadados, mas obteve %v", childRuns[0].ID, call.Metadata["run_id"])
+	}
+	// Templates são resolvidos e as demais strings repassadas como estão
+	if inputs := childRuns[0].Inputs; inputs["name"] != "ana" || inputs["greeting"] != "hello" {
+		t.Errorf("Inputs inesperados: %v", inputs)
+	}
+}
+
+func TestSubWorkflowRecursion(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	for _, workflow := range []*models.Workflow{
+		{ID: "ping", Name: "Ping", Steps: []models.Step{callStep("call", "pong", nil)}},
+		{ID: "pong", Name: "Pong", Steps: []models.Step{callStep("call", "ping", nil)}},
+	} {
+		if err := engine.RegisterWorkflow(workflow); err != nil {
+			t.Fatalf("Erro ao registrar workflow: %v", err)
+		}
+	}
+
+	_, err := engine.ExecuteWorkflow(context.Background(), "ping")
+	if err == nil {
+		t.Fatal("Esperava erro de invocação recursiva")
+	}
+	if !strings.Contains(err.Error(), "recursive workflow invocation: ping -> pong -> ping") {
+		t.Errorf("Erro inesperado: %v", err)
+	}
+}
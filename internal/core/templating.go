@@ -0,0 +1,99 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/carloskvasir/goflow/internal/expression"
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// renderConfig renders the {{ expression }} placeholders of a step config
+// against the data of the execution context, the environment ("env") and the
+// engine secrets ("secrets"). Raw keys of the step type are left untouched,
+// and its string keys are rendered to strings even when made of a single
+// placeholder.
+func (w *WorkflowEngine) renderConfig(step models.Step, data map[string]interface{}) (models.StepConfig, error) {
+	w.mu.RLock()
+	rawKeys := w.rawConfigKeys[step.Type]
+	stringKeys := w.stringConfigKeys[step.Type]
+	w.mu.RUnlock()
+
+	templated := make(map[string]interface{}, len(step.Config))
+	raw := make(map[string]interface{})
+	stringValues := make(map[string]string)
+	for key, value := range step.Config {
+		template, isString := value.(string)
+		switch {
+		case hasKey(rawKeys, key):
+			raw[key] = value
+		case isString && hasKey(stringKeys, key):
+			stringValues[key] = template
+		default:
+			templated[key] = value
+		}
+	}
+
+	env := w.templateGlobals()
+	for key, value := range data {
+		env[key] = value
+	}
+
+	rendered, err := expression.RenderValue(templated, env)
+	if err != nil {
+		return nil, fmt.Errorf("step %s: config %w", step.ID, err)
+	}
+
+	config := models.StepConfig(rendered.(map[string]interface{}))
+	for key, template := range stringValues {
+		if config[key], err = expression.RenderString(template, env); err != nil {
+			return nil, fmt.Errorf("step %s: config %w", step.ID, &expression.FieldError{Field: key, Err: err})
+		}
+	}
+	for key, value := range raw {
+		config[key] = value
+	}
+	return config, nil
+}
+
+// templateGlobals returns the values available to every template: the
+// environment ("env") and the engine secrets ("secrets").
+func (w *WorkflowEngine) templateGlobals() map[string]interface{} {
+	w.mu.RLock()
+	secrets := make(map[string]interface{}, len(w.secrets))
+	for name, value := range w.secrets {
+		secrets[name] = value
+	}
+	w.mu.RUnlock()
+
+	return map[string]interface{}{
+		"env":     environment(),
+		"secrets": secrets,
+	}
+}
+
+// hasKey reports whether a config key is in a list of keys of a step type
+func hasKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// environment returns the environment variables of the process
+func environment() map[string]interface{} {
+	variables := make(map[string]interface{})
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok {
+			variables[name] = value
+		}
+	}
+	return variables
+}
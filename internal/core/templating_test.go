@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+func TestStepConfigTemplating(t *testing.T) {
+	var authorization, path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		path = r.URL.RequestURI()
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("GOFLOW_TEST_API_VERSION", "v2")
+	engine := NewWorkflowEngine(WithSecrets(map[string]string{"api_key": "s3cret"}))
+
+	workflow := &models.Workflow{
+		ID:   "templated",
+		Name: "Templated",
+		Steps: []models.Step{
+			{ID: "login", Type: "echo", Config: map[string]interface{}{"message": "token-123"}, Next: []models.Transition{{Step: "fetch"}}},
+			{
+				ID:   "fetch",
+				Type: "rest",
+				Config: map[string]interface{}{
+					"method": "GET",
+					"url":    server.URL + "/{{ env.GOFLOW_TEST_API_VERSION }}/cities/{{ lower(inputs.city) }}",
+					"params": map[string]interface{}{"key": "{{ secrets.api_key }}"},
+					"headers": map[string]interface{}{
+						"Authorization": "Bearer {{ steps.login.data }}",
+					},
+				},
+				Next:     []models.Transition{{Step: "summary"}},
+				Required: true,
+			},
+			{
+				ID:   "summary",
+				Type: "transform",
+				Config: map[string]interface{}{
+					"template": "ok={{.ok}}",
+					"mapping":  map[string]interface{}{"ok": "fetch.data.ok"},
+				},
+			},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflowWithInputs(context.Background(), workflow.ID, map[string]interface{}{"city": "Recife"})
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	if authorization != "Bearer token-123" {
+		t.Errorf("Header Authorization inesperado: %q", authorization)
+	}
+	if path != "/v2/cities/recife?key=s3cret" {
+		t.Errorf("Caminho inesperado: %q", path)
+	}
+	// O template do transform não passa pelo motor de expressões
+	if data := result.StepResults["summary"].Data; data != "ok=true" {
+		t.Errorf("Resultado inesperado do transform: %v", data)
+	}
+
+	// Referências não resolvidas falham o step indicando o step e o campo
+	_, err = engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err == nil || !strings.Contains(err.Error(), "step fetch: config field url: unresolved reference {{ lower(inputs.city) }}") {
+		t.Errorf("Erro inesperado: %v", err)
+	}
+}
+
+func TestStepConfigTemplatingStringKeys(t *testing.T) {
+	engine := NewWorkflowEngine()
+	workflow := &models.Workflow{
+		ID: "numbers",
+		Steps: []models.Step{
+			{ID: "count", Type: "echo", Config: map[string]interface{}{"message": "{{ inputs.n }}"}, Required: true},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	// Chaves de texto dos steps embutidos continuam texto mesmo com um único placeholder
+	result, err := engine.ExecuteWorkflowWithInputs(context.Background(), workflow.ID, map[string]interface{}{"n": 5})
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+	if data := result.StepResults["count"].Data; data != "5" {
+		t.Errorf("Esperava a mensagem \"5\", mas obteve %#v", data)
+	}
+}
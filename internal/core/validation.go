@@ -0,0 +1,303 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/carloskvasir/goflow/internal/expression"
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/schedule"
+	"github.com/carloskvasir/goflow/internal/steps"
+)
+
+// reservedStepIDs are the keys used by the execution context itself.
+var reservedStepIDs = map[string]bool{
+	"steps":    true,
+	"inputs":   true,
+	"metadata": true,
+	"item":     true,
+	"index":    true,
+	"env":      true,
+	"secrets":  true,
+}
+
+// ValidationError describes a single problem found in a workflow definition.
+type ValidationError struct {
+	StepID  string `json:"step_id,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	if e.StepID == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("step %s: %s", e.StepID, e.Message)
+}
+
+// ValidationErrors lists every problem found in a workflow definition.
+type ValidationErrors []ValidationError
+
+// Error implements the error interface.
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid workflow: %s", strings.Join(messages, "; "))
+}
+
+// ValidateWorkflow checks a workflow definition without registering it.
+// It returns ValidationErrors listing every problem found, or nil.
+func (w *WorkflowEngine) ValidateWorkflow(workflow *models.Workflow) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	return w.validateWorkflow(workflow)
+}
+
+// validateWorkflow checks the workflow definition. The caller must hold w.mu.
+func (w *WorkflowEngine) validateWorkflow(workflow *models.Workflow) error {
+	var errs ValidationErrors
+	addError := func(stepID, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{StepID: stepID, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if workflow.ID == "" {
+		addError("", "workflow ID cannot be empty")
+	}
+
+	if workflow.Schedule != nil {
+		if _, err := schedule.Parse(*workflow.Schedule); err != nil {
+			addError("", "invalid schedule: %v", err)
+		}
+	}
+
+	if workflow.InputSchema != nil {
+		if _, err := compileInputSchema(workflow); err != nil {
+			addError("", "invalid inputs schema: %v", err)
+		}
+	}
+
+	if workflow.Webhook != nil && workflow.Webhook.Path != "" {
+		for _, other := range w.workflows {
+			if other.ID != workflow.ID && other.Webhook != nil && other.Webhook.Path == workflow.Webhook.Path {
+				addError("", "webhook path %s is already used by workflow %s", workflow.Webhook.Path, other.ID)
+			}
+		}
+	}
+
+	// Verifica IDs, tipos e configuração de cada step
+	stepIDs := make(map[string]bool, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		if step.ID == "" {
+			addError("", "step ID cannot be empty")
+			continue
+		}
+		if stepIDs[step.ID] {
+			addError(step.ID, "duplicate step ID")
+		}
+		stepIDs[step.ID] = true
+
+		if reservedStepIDs[step.ID] {
+			addError(step.ID, "step ID is reserved")
+		}
+
+		factory, exists := w.stepTypes[step.Type]
+		if !exists {
+			addError(step.ID, "unknown step type: %s", step.Type)
+			continue
+		}
+		executor, err := factory(step.Config)
+		if err != nil {
+			addError(step.ID, "invalid config: %v", err)
+			continue
+		}
+
+		if user, ok := executor.(steps.ConnectionUser); ok {
+			if name := user.ConnectionName(); !strings.Contains(name, "{{") && !w.connections.hasConnection(workflow, name) {
+				addError(step.ID, "connection %s does not exist", name)
+			}
+		}
+
+		// Valida os steps filhos de steps compostos, como o foreach
+		if composite, ok := executor.(compositeStep); ok {
+			children := composite.childWorkflow()
+			children.Connections = workflow.Connections
+
+			var childErrs ValidationErrors
+			if errors.As(w.validateWorkflow(children), &childErrs) {
+				for _, childErr := range childErrs {
+					childErr.StepID = strings.TrimSuffix(step.ID+"/"+childErr.StepID, "/")
+					errs = append(errs, childErr)
+				}
+			}
+		}
+	}
+
+	// Verifica as referências entre steps
+	for _, step := range workflow.Steps {
+		for _, next := range step.Next {
+			if !stepIDs[next.Step] {
+				addError(step.ID, "next step %s does not exist", next.Step)
+			}
+			if next.When != "" {
+				if _, err := expression.Compile(next.When); err != nil {
+					addError(step.ID, "invalid condition for next step %s: %v", next.Step, err)
+				}
+			}
+		}
+		for _, onError := range step.OnError {
+			if !stepIDs[onError] {
+				addError(step.ID, "on_error step %s does not exist", onError)
+			}
+		}
+	}
+
+	// Steps de compensação ficam fora do grafo e só rodam se o workflow falhar
+	compensations := compensationSteps(workflow)
+	for _, step := range workflow.Steps {
+		if step.Compensate != "" {
+			switch {
+			case !stepIDs[step.Compensate]:
+				addError(step.ID, "compensate step %s does not exist", step.Compensate)
+			case step.Compensate == step.ID:
+				addError(step.ID, "step cannot compensate itself")
+			case compensations[step.ID]:
+				addError(step.ID, "compensation step cannot have its own compensation")
+			}
+		}
+		if compensations[step.ID] && (len(step.Next) > 0 || len(step.OnError) > 0) {
+			addError(step.ID, "compensation step cannot have next or on_error steps")
+		}
+		for _, next := range successors(step) {
+			if compensations[next] {
+				addError(step.ID, "compensation step %s cannot be used as a next or on_error step", next)
+			}
+		}
+	}
+
+	if workflow.Webhook != nil && workflow.Webhook.ResponseStep != "" && !stepIDs[workflow.Webhook.ResponseStep] {
+		addError("", "webhook response step %s does not exist", workflow.Webhook.ResponseStep)
+	}
+
+	for _, cycle := range findCycles(workflow) {
+		addError(cycle[0], "cycle detected: %s", strings.Join(cycle, " -> "))
+	}
+
+	for _, stepID := range findUnreachableSteps(workflow) {
+		addError(stepID, "step is unreachable")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// successors returns the IDs of the steps that can run after a step.
+func successors(step models.Step) []string {
+	next := make([]string, 0, len(step.Next)+len(step.OnError))
+	for _, transition := range step.Next {
+		next = append(next, transition.Step)
+	}
+	next = append(next, step.OnError...)
+	return next
+}
+
+// findCycles returns the cycles found in the workflow graph, each one as the
+// list of step IDs that form it, closed by its first step.
+func findCycles(workflow *models.Workflow) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	steps := make(map[string]models.Step, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		steps[step.ID] = step
+	}
+
+	var cycles [][]string
+	state := make(map[string]int, len(workflow.Steps))
+	var path []string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		path = append(path, id)
+
+		for _, next := range successors(steps[id]) {
+			if _, exists := steps[next]; !exists {
+				continue
+			}
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				for i := range path {
+					if path[i] == next {
+						cycle := append([]string{}, path[i:]...)
+						cycles = append(cycles, append(cycle, next))
+						break
+					}
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[id] = visited
+	}
+
+	for _, step := range workflow.Steps {
+		if state[step.ID] == unvisited {
+			visit(step.ID)
+		}
+	}
+	return cycles
+}
+
+// findUnreachableSteps returns the steps that cannot be reached from any
+// initial step of the workflow.
+func findUnreachableSteps(workflow *models.Workflow) []string {
+	steps := make(map[string]models.Step, len(workflow.Steps))
+	referenced := make(map[string]bool)
+	for _, step := range workflow.Steps {
+		steps[step.ID] = step
+		for _, next := range successors(step) {
+			referenced[next] = true
+		}
+	}
+
+	compensations := compensationSteps(workflow)
+	reached := make(map[string]bool)
+	var queue []string
+	for _, step := range workflow.Steps {
+		if !referenced[step.ID] && !compensations[step.ID] {
+			queue = append(queue, step.ID)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if reached[id] {
+			continue
+		}
+		reached[id] = true
+		queue = append(queue, successors(steps[id])...)
+	}
+
+	var unreachable []string
+	for _, step := range workflow.Steps {
+		if step.ID != "" && !reached[step.ID] && !compensations[step.ID] {
+			unreachable = append(unreachable, step.ID)
+		}
+	}
+	return unreachable
+}
@@ -7,26 +7,101 @@ package core
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/retry"
+	"github.com/carloskvasir/goflow/internal/schedule"
 	"github.com/carloskvasir/goflow/internal/steps"
+	"github.com/carloskvasir/goflow/internal/store"
+	"github.com/santhosh-tekuri/jsonschema/v5"
 )
 
 // WorkflowEngine is responsible for executing workflows and managing their lifecycle.
 type WorkflowEngine struct {
 	workflows map[string]*models.Workflow
-	results   map[string]*models.WorkflowResult
-	mu        sync.RWMutex
+	versions  map[string]int
+	runs      map[string]*models.Run
+	cancels   map[string]context.CancelFunc
+	store     store.Store
+	retention RunRetention
+	stepTypes map[string]steps.StepFactory
+	clock     schedule.Clock
+	secrets   map[string]string
+
+	// rawConfigKeys lists, per step type, the config keys that are not templated
+	rawConfigKeys map[string][]string
+	// stringConfigKeys lists, per built-in step type, the config keys whose
+	// templates always render to a string
+	stringConfigKeys map[string][]string
+
+	// inputSchemas caches the compiled input schema of each workflow version
+	inputSchemas map[schemaKey]*jsonschema.Schema
+
+	schedules   *schedule.Scheduler
+	connections *connectionPool
+	mu          sync.RWMutex
+}
+
+// EngineOption configures optional behaviour of the workflow engine.
+type EngineOption func(*WorkflowEngine)
+
+// WithRunRetention sets how many finished runs are kept per workflow and for how long.
+func WithRunRetention(retention RunRetention) EngineOption {
+	return func(w *WorkflowEngine) {
+		w.retention = retention
+	}
+}
+
+// WithStore sets the backend used to persist workflows and runs. Call Load
+// after creating the engine to restore what the store already holds.
+func WithStore(s store.Store) EngineOption {
+	return func(w *WorkflowEngine) {
+		w.store = s
+	}
+}
+
+// WithClock sets the clock used to fire workflow schedules.
+func WithClock(clock schedule.Clock) EngineOption {
+	return func(w *WorkflowEngine) {
+		w.clock = clock
+	}
+}
+
+// WithSecrets sets the secrets available to step config templates as
+// {{ secrets.name }}.
+func WithSecrets(secrets map[string]string) EngineOption {
+	return func(w *WorkflowEngine) {
+		w.secrets = secrets
+	}
 }
 
 // NewWorkflowEngine creates a new instance of the workflow engine.
-func NewWorkflowEngine() *WorkflowEngine {
-	return &WorkflowEngine{
+func NewWorkflowEngine(opts ...EngineOption) *WorkflowEngine {
+	engine := &WorkflowEngine{
 		workflows: make(map[string]*models.Workflow),
-		results:   make(map[string]*models.WorkflowResult),
+		versions:  make(map[string]int),
+		runs:      make(map[string]*models.Run),
+		cancels:   make(map[string]context.CancelFunc),
+		store:     store.NewMemoryStore(),
+		retention: RunRetention{MaxRuns: DefaultMaxRuns},
+		stepTypes: make(map[string]steps.StepFactory),
+		clock:     schedule.RealClock(),
+
+		rawConfigKeys:    make(map[string][]string),
+		stringConfigKeys: make(map[string][]string),
+		inputSchemas:     make(map[schemaKey]*jsonschema.Schema),
+		connections:      newConnectionPool(),
 	}
+	engine.registerBuiltinStepTypes()
+
+	for _, opt := range opts {
+		opt(engine)
+	}
+	engine.schedules = schedule.NewScheduler(engine, engine.clock)
+	return engine
 }
 
 // RegisterWorkflow registers a new workflow in the engine.
@@ -42,16 +117,86 @@ func (w *WorkflowEngine) RegisterWorkflow(workflow *models.Workflow) error {
 		return fmt.Errorf("workflow with ID %s already exists", workflow.ID)
 	}
 
+	if err := w.validateWorkflow(workflow); err != nil {
+		return err
+	}
+
+	workflow.Version = w.versions[workflow.ID] + 1
 	workflow.Status = models.StatusPending
 	workflow.CreatedAt = time.Now()
 	workflow.UpdatedAt = workflow.CreatedAt
 
+	if err := w.store.SaveWorkflow(workflow); err != nil {
+		return fmt.Errorf("error saving workflow %s: %w", workflow.ID, err)
+	}
+
+	w.versions[workflow.ID] = workflow.Version
 	w.workflows[workflow.ID] = workflow
+
+	// O schema dos inputs é compilado uma vez por versão, e não a cada execução
+	if workflow.InputSchema != nil {
+		if _, err := w.inputSchema(workflow); err != nil {
+			return err
+		}
+	}
+
+	w.connections.closeWorkflow(workflow.ID)
+	return w.schedules.Set(workflow.ID, workflow.Schedule)
+}
+
+// Load restores the workflows and runs persisted in the store.
+func (w *WorkflowEngine) Load() error {
+	workflows, err := w.store.ListWorkflows()
+	if err != nil {
+		return fmt.Errorf("error loading workflows: %w", err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, workflow := range workflows {
+		runs, err := w.store.ListRuns(workflow.ID)
+		if err != nil {
+			return fmt.Errorf("error loading runs of workflow %s: %w", workflow.ID, err)
+		}
+
+		w.workflows[workflow.ID] = workflow
+		w.versions[workflow.ID] = workflow.Version
+		if workflow.InputSchema != nil {
+			if _, err := w.inputSchema(workflow); err != nil {
+				return err
+			}
+		}
+		for _, run := range runs {
+			w.runs[run.ID] = run
+		}
+		if err := w.schedules.Set(workflow.ID, workflow.Schedule); err != nil {
+			return fmt.Errorf("error loading schedule of workflow %s: %w", workflow.ID, err)
+		}
+	}
 	return nil
 }
 
-// ExecuteWorkflow executes a specific workflow.
+// Close stops the scheduler and releases the resources held by the engine's
+// connections and store.
+func (w *WorkflowEngine) Close() error {
+	w.schedules.Stop()
+	connectionsErr := w.connections.close()
+	if err := w.store.Close(); err != nil {
+		return err
+	}
+	return connectionsErr
+}
+
+// ExecuteWorkflow executes a specific workflow. Every execution creates a new
+// run, identified by the RunID of the returned result.
 func (w *WorkflowEngine) ExecuteWorkflow(ctx context.Context, workflowID string) (*models.WorkflowResult, error) {
+	return w.ExecuteWorkflowWithInputs(ctx, workflowID, nil)
+}
+
+// ExecuteWorkflowWithInputs executes a workflow with the given inputs, available
+// to the steps under "inputs", and waits for it to finish.
+func (w *WorkflowEngine) ExecuteWorkflowWithInputs(ctx context.Context, workflowID string, inputs map[string]interface{}) (*models.WorkflowResult, error) {
 	w.mu.Lock()
 	workflow, exists := w.workflows[workflowID]
 	if !exists {
@@ -59,28 +204,50 @@ func (w *WorkflowEngine) ExecuteWorkflow(ctx context.Context, workflowID string)
 		return nil, fmt.Errorf("workflow %s not found", workflowID)
 	}
 
-	result := &models.WorkflowResult{
-		WorkflowID:  workflowID,
-		Status:      models.StatusRunning,
-		StepResults: make(map[string]models.StepResult),
-		StartTime:   time.Now(),
+	run, err := w.createRun(workflow, inputs, "")
+	if err != nil {
+		w.mu.Unlock()
+		return nil, err
 	}
-	w.results[workflowID] = result
+	ctx, cancel := w.cancellable(ctx, run)
 	w.mu.Unlock()
+	defer cancel()
 
-	err := w.executeSteps(ctx, workflow, result)
+	return w.executeRun(ctx, workflow, run)
+}
 
-	w.mu.Lock()
-	result.EndTime = time.Now()
-	if err != nil {
-		result.Status = models.StatusFailed
-		result.Error = err.Error()
-	} else {
-		result.Status = models.StatusCompleted
+// executeRun executes the steps of a run and records its outcome.
+func (w *WorkflowEngine) executeRun(ctx context.Context, workflow *models.Workflow, run *models.Run) (*models.WorkflowResult, error) {
+	if workflow.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, workflow.Timeout)
+		defer cancel()
 	}
-	w.mu.Unlock()
 
-	return result, err
+	ctx = withRunFrame(ctx, workflow.ID, run.ID)
+	execCtx := NewExecutionContext(workflow, run.Inputs)
+	execCtx.RunID = run.ID
+
+	scheduler := w.newStepScheduler(workflow, execCtx, run.Result)
+	scheduler.checkpoint = func() { w.checkpointRun(run) }
+	err := scheduler.run(ctx)
+
+	status := runStatus(ctx, err)
+	if status == models.StatusFailed || status == models.StatusTimedOut {
+		w.compensate(ctx, workflow, execCtx, run, scheduler.completed)
+	}
+
+	outputs, outputsErr := resolveOutputs(workflow, execCtx)
+	if outputsErr != nil && err == nil {
+		err, status = outputsErr, models.StatusFailed
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	run.Result.Outputs = outputs
+	w.finishRun(run, status, err)
+	return copyRun(run).Result, err
 }
 
 // GetWorkflow retorna um workflow pelo seu ID
@@ -92,6 +259,22 @@ func (w *WorkflowEngine) GetWorkflow(id string) (*models.Workflow, bool) {
 	return workflow, exists
 }
 
+// ListWorkflows returns the registered workflows, ordered by ID
+func (w *WorkflowEngine) ListWorkflows() []*models.Workflow {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	workflows := make([]*models.Workflow, 0, len(w.workflows))
+	for _, workflow := range w.workflows {
+		workflows = append(workflows, workflow)
+	}
+
+	sort.Slice(workflows, func(i, j int) bool {
+		return workflows[i].ID < workflows[j].ID
+	})
+	return workflows
+}
+
 // DeleteWorkflow removes a workflow from the engine
 func (w *WorkflowEngine) DeleteWorkflow(id string) error {
 	w.mu.Lock()
@@ -101,230 +284,112 @@ func (w *WorkflowEngine) DeleteWorkflow(id string) error {
 		return fmt.Errorf("workflow %s not found", id)
 	}
 
-	delete(w.workflows, id)
-	delete(w.results, id)
-	return nil
-}
-
-// executeSteps executes the workflow steps.
-func (w *WorkflowEngine) executeSteps(ctx context.Context, workflow *models.Workflow, result *models.WorkflowResult) error {
-	completed := make(map[string]bool)
-	var mu sync.Mutex
-	var wg sync.WaitGroup
-	errChan := make(chan error, len(workflow.Steps))
-
-	// Função para executar um step e seus dependentes
-	var executeStepAndDependents func(step models.Step)
-	executeStepAndDependents = func(step models.Step) {
-		defer wg.Done()
-
-		// Verifica se o step já foi executado
-		mu.Lock()
-		if completed[step.ID] {
-			mu.Unlock()
-			return
-		}
-		mu.Unlock()
-
-		// Verifica se todas as dependências foram completadas
-		if !w.canExecuteStep(&step, completed, workflow) {
-			wg.Add(1)
-			go executeStepAndDependents(step)
-			return
-		}
-
-		// Executa o step
-		if err := w.executeStep(ctx, step, workflow, result, completed); err != nil {
-			errChan <- fmt.Errorf("error in step %s: %w", step.ID, err)
-			return
-		}
-
-		// Executa os próximos steps
-		for _, nextStepID := range step.Next {
-			nextStep := w.findStep(workflow, nextStepID)
-			if nextStep != nil {
-				wg.Add(1)
-				go executeStepAndDependents(*nextStep)
-			}
-		}
-	}
-
-	// Inicia a execução pelos steps iniciais
-	initialSteps := w.findInitialSteps(workflow)
-	for _, step := range initialSteps {
-		wg.Add(1)
-		go executeStepAndDependents(step)
+	if err := w.store.DeleteWorkflow(id); err != nil {
+		return fmt.Errorf("error deleting workflow %s: %w", id, err)
 	}
 
-	wg.Wait()
-	close(errChan)
-
-	// Retorna o primeiro erro encontrado
-	for err := range errChan {
-		if err != nil {
-			return err
+	delete(w.workflows, id)
+	w.forgetInputSchemas(id)
+	w.schedules.Remove(id)
+	w.connections.closeWorkflow(id)
+	for runID, run := range w.runs {
+		if run.WorkflowID == id {
+			w.deleteRun(runID)
 		}
 	}
-
 	return nil
 }
 
-// executeStep executes a single step.
-func (w *WorkflowEngine) executeStep(ctx context.Context, step models.Step, workflow *models.Workflow, result *models.WorkflowResult, completed map[string]bool) error {
-	if ctx.Err() != nil {
-		return ctx.Err()
-	}
-
-	stepResult := models.StepResult{
-		StepID:    step.ID,
-		Status:    models.StatusRunning,
-		StartTime: time.Now(),
-		Attempts:  0,
-	}
-
-	var err error
-	if step.Retry != nil {
-		err = w.executeWithRetry(ctx, step, &stepResult)
-	} else {
-		err = w.executeSingleStep(step, &stepResult)
-	}
-
-	stepResult.EndTime = time.Now()
-	if err != nil {
-		stepResult.Status = models.StatusFailed
-		stepResult.Error = err.Error()
-		if step.Required {
-			return err
-		}
-	} else {
-		stepResult.Status = models.StatusCompleted
-	}
-
-	w.mu.Lock()
-	result.StepResults[step.ID] = stepResult
-	completed[step.ID] = true
-	w.mu.Unlock()
-
-	if err == nil {
-		for _, nextStepID := range step.Next {
-			nextStep := w.findStep(workflow, nextStepID)
-			if nextStep != nil && w.canExecuteStep(nextStep, completed, workflow) {
-				if err := w.executeStep(ctx, *nextStep, workflow, result, completed); err != nil {
-					return err
-				}
-			}
-		}
-	} else if len(step.OnError) > 0 {
-		for _, errorStepID := range step.OnError {
-			errorStep := w.findStep(workflow, errorStepID)
-			if errorStep != nil {
-				if err := w.executeStep(ctx, *errorStep, workflow, result, completed); err != nil {
-					return err
-				}
-			}
-		}
+// executeWithRetry executes a step with retry logic. Only the errors the
+// retry policy of the step considers transient are retried, and the outcome
+// of every attempt is recorded in the "attempts" metadata of the step.
+func (w *WorkflowEngine) executeWithRetry(ctx context.Context, step models.Step, execCtx *ExecutionContext, result *models.StepResult) error {
+	policy := retry.Policy{
+		MaxAttempts:     step.Retry.MaxAttempts,
+		Delay:           step.Retry.Delay,
+		MaxDelay:        step.Retry.MaxDelay,
+		Multiplier:      step.Retry.Multiplier,
+		Jitter:          step.Retry.Jitter,
+		RetryableStatus: step.Retry.RetryOnStatus,
+		Classes:         step.Retry.RetryOn,
 	}
 
-	return nil
-}
+	attempts, err := retry.Do(ctx, policy, func(ctx context.Context, attempt int) error {
+		result.Attempts = attempt
+		return w.executeSingleStep(ctx, step, execCtx, result)
+	})
 
-// canExecuteStep checks if a step can be executed based on completed dependencies.
-func (w *WorkflowEngine) canExecuteStep(step *models.Step, completed map[string]bool, workflow *models.Workflow) bool {
-	for _, s := range workflow.Steps {
-		for _, next := range s.Next {
-			if next == step.ID && !completed[s.ID] {
-				return false
-			}
-		}
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
 	}
-	return true
-}
+	result.Metadata["attempts"] = attempts
 
-// executeWithRetry executes a step with retry logic.
-func (w *WorkflowEngine) executeWithRetry(ctx context.Context, step models.Step, result *models.StepResult) error {
-	var lastErr error
-	delay := step.Retry.Delay
-
-	maxAttempts := step.Retry.MaxAttempts
-	if maxAttempts <= 0 {
-		maxAttempts = 1
-	}
-
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		result.Attempts = attempt + 1
-		
-		if err := w.executeSingleStep(step, result); err != nil {
-			lastErr = err
-			if attempt < maxAttempts-1 { // Só espera se houver mais tentativas
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case <-time.After(delay):
-					if step.Retry.Multiplier > 0 {
-						delay = time.Duration(float64(delay) * step.Retry.Multiplier)
-						if step.Retry.MaxDelay > 0 && delay > step.Retry.MaxDelay {
-							delay = step.Retry.MaxDelay
-						}
-					}
-					continue
-				}
-			}
-		} else {
-			return nil
-		}
+	switch {
+	case err == nil:
+		return nil
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case len(attempts) > 1:
+		return fmt.Errorf("max retry attempts reached: %w", err)
+	default:
+		return err
 	}
-
-	return fmt.Errorf("max retry attempts reached: %v", lastErr)
 }
 
-// executeSingleStep executes a single step without retry.
-func (w *WorkflowEngine) executeSingleStep(step models.Step, result *models.StepResult) error {
-	var stepExecutor interface{ Execute(map[string]interface{}) (*models.StepResult, error) }
-
-	switch step.Type {
-	case "rest":
-		stepExecutor = steps.NewRestStep(step.Config)
-	case "transform":
-		stepExecutor = steps.NewTransformStep(step.Config)
-	case "echo":
-		stepExecutor = steps.NewEchoStep(step.Config)
-	default:
-		return fmt.Errorf("unknown step type: %s", step.Type)
+// executeSingleStep executes a single attempt of a step without retry, handing
+// it the data accumulated so far in the execution context. The step config is
+// rendered against that data first. The attempt is bounded by the step timeout;
+// once its context is done the engine stops waiting for the executor.
+func (w *WorkflowEngine) executeSingleStep(ctx context.Context, step models.Step, execCtx *ExecutionContext, result *models.StepResult) error {
+	data := execCtx.Data()
+	config, err := w.renderConfig(step, data)
+	if err != nil {
+		return retry.Permanent(err)
 	}
+	step.Config = config
 
-	stepResult, err := stepExecutor.Execute(nil) // TODO: Passar contexto adequado
+	stepExecutor, err := w.newStepExecutor(step)
 	if err != nil {
+		return retry.Permanent(err)
+	}
+	if err := w.connectStep(ctx, step, stepExecutor); err != nil {
 		return err
 	}
 
-	result.Data = stepResult.Data
-	return nil
-}
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, step.Timeout)
+		defer cancel()
+	}
 
-// findInitialSteps finds steps that are not referenced as "next" in any other step.
-func (w *WorkflowEngine) findInitialSteps(workflow *models.Workflow) []models.Step {
-	nextSteps := make(map[string]bool)
-	for _, step := range workflow.Steps {
-		for _, next := range step.Next {
-			nextSteps[next] = true
-		}
+	type attempt struct {
+		result *models.StepResult
+		err    error
 	}
+	done := make(chan attempt, 1)
+	go func() {
+		// Um step que entra em pânico falha sem derrubar o processo
+		defer func() {
+			if r := recover(); r != nil {
+				done <- attempt{err: retry.Permanent(fmt.Errorf("step panicked: %v", r))}
+			}
+		}()
 
-	var initialSteps []models.Step
-	for _, step := range workflow.Steps {
-		if !nextSteps[step.ID] {
-			initialSteps = append(initialSteps, step)
-		}
+		stepResult, err := stepExecutor.Execute(ctx, data)
+		done <- attempt{result: stepResult, err: err}
+	}()
+
+	var outcome attempt
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case outcome = <-done:
 	}
-	return initialSteps
-}
 
-// findStep finds a step by its ID.
-func (w *WorkflowEngine) findStep(workflow *models.Workflow, stepID string) *models.Step {
-	for i := range workflow.Steps {
-		if workflow.Steps[i].ID == stepID {
-			return &workflow.Steps[i]
-		}
+	// Steps podem devolver dados parciais junto com o erro
+	if outcome.result != nil {
+		result.Data = outcome.result.Data
+		result.Metadata = outcome.result.Metadata
 	}
-	return nil
+	return outcome.err
 }
@@ -2,10 +2,18 @@ package core
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/retry"
+	"github.com/carloskvasir/goflow/internal/steps"
+	"github.com/carloskvasir/goflow/internal/store"
 )
 
 func TestWorkflowEngine(t *testing.T) {
@@ -32,7 +40,7 @@ func TestWorkflowEngine(t *testing.T) {
 				Config: map[string]interface{}{
 					"message": "Hello from Step 2",
 				},
-				Next: []string{"step3"},
+				Next: []models.Transition{{Step: "step3"}},
 			},
 			{
 				ID:   "step3",
@@ -95,9 +103,9 @@ func TestWorkflowEngineWithRetry(t *testing.T) {
 				},
 				Retry: &models.RetryConfig{
 					MaxAttempts: 3,
-					Delay:      time.Millisecond * 100,
-					MaxDelay:   time.Second,
-					Multiplier: 2.0,
+					Delay:       time.Millisecond * 100,
+					MaxDelay:    time.Second,
+					Multiplier:  2.0,
 				},
 			},
 		},
@@ -124,3 +132,725 @@ func TestWorkflowEngineWithRetry(t *testing.T) {
 		t.Errorf("Esperava 1 tentativa, mas obteve %d", stepResult.Attempts)
 	}
 }
+
+// flakyStep falha com os erros configurados, um por tentativa, e depois conclui
+type flakyStep struct {
+	mu       sync.Mutex
+	failures []error
+	calls    int
+}
+
+func (s *flakyStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls <= len(s.failures) {
+		return nil, s.failures[s.calls-1]
+	}
+	return &models.StepResult{Status: models.StatusCompleted, Data: "ok"}, nil
+}
+
+func TestWorkflowEngineRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		failures []error
+		status   models.WorkflowStatus
+		attempts int
+	}{
+		{"erros transitórios", []error{&retry.StatusError{StatusCode: 503}, fmt.Errorf("connection lost: %w", io.ErrUnexpectedEOF)}, models.StatusCompleted, 3},
+		{"status não retentável", []error{&retry.StatusError{StatusCode: 404}}, models.StatusFailed, 1},
+		{"erro permanente", []error{retry.Permanent(errors.New("bad input"))}, models.StatusFailed, 1},
+		{"erro genérico", []error{errors.New("invalid data")}, models.StatusFailed, 1},
+		{"tentativas esgotadas", []error{&retry.StatusError{StatusCode: 502}, &retry.StatusError{StatusCode: 503}, &retry.StatusError{StatusCode: 504}}, models.StatusFailed, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewWorkflowEngine()
+			step := &flakyStep{failures: tt.failures}
+			engine.RegisterStepType("flaky", func(config models.StepConfig) (steps.StepExecutor, error) {
+				return step, nil
+			})
+
+			workflow := &models.Workflow{
+				ID: "flaky",
+				Steps: []models.Step{{
+					ID:       "call",
+					Type:     "flaky",
+					Required: true,
+					Retry:    &models.RetryConfig{MaxAttempts: 3, Delay: time.Millisecond},
+				}},
+			}
+			if err := engine.RegisterWorkflow(workflow); err != nil {
+				t.Fatalf("Erro ao registrar workflow: %v", err)
+			}
+
+			result, _ := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+			stepResult := result.StepResults["call"]
+			if stepResult.Status != tt.status {
+				t.Errorf("Status esperado %s, mas obteve %s", tt.status, stepResult.Status)
+			}
+			if stepResult.Attempts != tt.attempts || step.calls != tt.attempts {
+				t.Errorf("Esperava %d tentativas, mas obteve %d (%d chamadas)", tt.attempts, stepResult.Attempts, step.calls)
+			}
+
+			// Cada tentativa fica registrada nos metadados do step
+			attempts, _ := stepResult.Metadata["attempts"].([]retry.Attempt)
+			if len(attempts) != tt.attempts {
+				t.Fatalf("Esperava %d tentativas nos metadados, mas obteve %v", tt.attempts, stepResult.Metadata)
+			}
+			for i, failure := range tt.failures {
+				if i < len(attempts) && attempts[i].Error != failure.Error() {
+					t.Errorf("Erro inesperado na tentativa %d: %q", i+1, attempts[i].Error)
+				}
+			}
+		})
+	}
+}
+
+func TestWorkflowEngineExecutionContext(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	// O step de transformação consome a saída do step anterior
+	workflow := &models.Workflow{
+		ID:   "context-workflow",
+		Name: "Context Workflow",
+		Steps: []models.Step{
+			{
+				ID:   "greet",
+				Name: "Greet",
+				Type: "echo",
+				Config: map[string]interface{}{
+					"message": "Hello",
+				},
+				Next: []models.Transition{{Step: "format"}},
+			},
+			{
+				ID:   "format",
+				Name: "Format",
+				Type: "transform",
+				Config: map[string]interface{}{
+					"template": "{{.greeting}}, {{.status}}!",
+					"mapping": map[string]interface{}{
+						"greeting": "greet.data",
+						"status":   "steps.greet.status",
+					},
+				},
+			},
+		},
+	}
+
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	expected := "Hello, completed!"
+	if data := result.StepResults["format"].Data; data != expected {
+		t.Errorf("Esperava %q, mas obteve %q", expected, data)
+	}
+}
+
+// upperStep é um step personalizado usado nos testes do registro de tipos
+type upperStep struct {
+	config models.StepConfig
+}
+
+func (s *upperStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	return &models.StepResult{
+		Status: models.StatusCompleted,
+		Data:   strings.ToUpper(s.config["message"].(string)),
+	}, nil
+}
+
+func TestWorkflowEngineCustomStepType(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	workflow := &models.Workflow{
+		ID:   "custom-workflow",
+		Name: "Custom Workflow",
+		Steps: []models.Step{
+			{
+				ID:   "shout",
+				Name: "Shout",
+				Type: "upper",
+				Config: map[string]interface{}{
+					"message": "hello",
+				},
+			},
+		},
+	}
+
+	// Tipos desconhecidos devem ser rejeitados no registro do workflow
+	if err := engine.RegisterWorkflow(workflow); err == nil {
+		t.Fatal("Esperava erro ao registrar workflow com tipo de step desconhecido")
+	}
+
+	factory := func(config models.StepConfig) (steps.StepExecutor, error) {
+		return &upperStep{config: config}, nil
+	}
+	if err := engine.RegisterStepType("upper", factory); err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+
+	if err := engine.RegisterStepType("upper", factory); err == nil {
+		t.Error("Esperava erro ao registrar tipo de step duplicado")
+	}
+
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	if data := result.StepResults["shout"].Data; data != "HELLO" {
+		t.Errorf("Esperava %q, mas obteve %q", "HELLO", data)
+	}
+}
+
+func TestWorkflowEngineValidation(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	workflow := &models.Workflow{
+		ID:   "invalid-workflow",
+		Name: "Invalid Workflow",
+		Steps: []models.Step{
+			{ID: "start", Type: "echo", Config: map[string]interface{}{"message": "hi"}, Next: []models.Transition{{Step: "missing"}}},
+			{ID: "start", Type: "echo", Config: map[string]interface{}{"message": "hi"}},
+			{ID: "loop-a", Type: "echo", Config: map[string]interface{}{"message": "a"}, Next: []models.Transition{{Step: "loop-b"}}},
+			{ID: "loop-b", Type: "echo", Config: map[string]interface{}{"message": "b"}, Next: []models.Transition{{Step: "loop-a"}}},
+			{ID: "call", Type: "rest", Config: map[string]interface{}{"method": "GET"}},
+			{ID: "other", Type: "unknown"},
+			{ID: "bad-echo", Type: "echo", Config: map[string]interface{}{"message": 123}},
+			{ID: "bad-transform", Type: "transform", Config: map[string]interface{}{"template": "x", "mapping": "x"}},
+			{ID: "bad-path", Type: "transform", Config: map[string]interface{}{"template": "x", "mapping": map[string]interface{}{"a": 1}}},
+			{ID: "bad-rest", Type: "rest", Config: map[string]interface{}{"method": 1, "url": "/"}},
+		},
+	}
+
+	err := engine.ValidateWorkflow(workflow)
+	var validationErrs ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Esperava ValidationErrors, mas obteve %v", err)
+	}
+
+	expected := map[string][]string{
+		"start":  {"next step missing does not exist", "duplicate step ID"},
+		"loop-a": {"cycle detected: loop-a -> loop-b -> loop-a", "step is unreachable"},
+		"loop-b": {"step is unreachable"},
+		"call":   {"invalid config: missing required config keys: url"},
+		"other":  {"unknown step type: unknown"},
+		// Os tipos das chaves obrigatórias também são validados
+		"bad-echo":      {"invalid config: config key message must be a string"},
+		"bad-transform": {"invalid config: config key mapping must be an object"},
+		"bad-path":      {"invalid config: config key mapping.a must be a string"},
+		"bad-rest":      {"invalid config: config key method must be a string"},
+	}
+	for stepID, messages := range expected {
+		for _, message := range messages {
+			found := false
+			for _, validationErr := range validationErrs {
+				if validationErr.StepID == stepID && validationErr.Message == message {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Esperava erro %q no step %s, erros: %v", message, stepID, validationErrs)
+			}
+		}
+	}
+
+	// O workflow inválido não deve ser registrado
+	if err := engine.RegisterWorkflow(workflow); err == nil {
+		t.Error("Esperava erro ao registrar workflow inválido")
+	}
+}
+
+func TestWorkflowEngineRuns(t *testing.T) {
+	engine := NewWorkflowEngine(WithRunRetention(RunRetention{MaxRuns: 2}))
+
+	workflow := &models.Workflow{
+		ID:   "runs-workflow",
+		Name: "Runs Workflow",
+		Steps: []models.Step{
+			{ID: "step1", Type: "echo", Config: map[string]interface{}{"message": "hi"}},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	// Cada execução gera uma run com ID próprio
+	runIDs := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+		if err != nil {
+			t.Fatalf("Erro ao executar workflow: %v", err)
+		}
+		if runIDs[result.RunID] {
+			t.Errorf("ID de execução repetido: %s", result.RunID)
+		}
+		runIDs[result.RunID] = true
+		time.Sleep(time.Millisecond)
+	}
+
+	// A política de retenção mantém apenas as duas execuções mais recentes
+	runs := engine.ListRuns(workflow.ID)
+	if len(runs) != 2 {
+		t.Fatalf("Esperava 2 execuções, mas obteve %d", len(runs))
+	}
+
+	run, exists := engine.GetRun(runs[0].ID)
+	if !exists {
+		t.Fatalf("Execução %s não encontrada", runs[0].ID)
+	}
+	if run.Status != models.StatusCompleted || run.WorkflowVersion != 1 {
+		t.Errorf("Execução inesperada: status %s, versão %d", run.Status, run.WorkflowVersion)
+	}
+	if run.Result.StepResults["step1"].Data != "hi" {
+		t.Errorf("Resultado inesperado: %v", run.Result.StepResults)
+	}
+}
+
+// blockStep bloqueia até que o canal seja fechado
+type blockStep struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	close(s.started)
+	<-s.release
+	return &models.StepResult{Status: models.StatusCompleted}, nil
+}
+
+// waitRun espera até que a execução termine
+func waitRun(t *testing.T, engine *WorkflowEngine, runID string) *models.Run {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		run, exists := engine.GetRun(runID)
+		if !exists {
+			t.Fatalf("Execução %s não encontrada", runID)
+		}
+		if run.Status != models.StatusRunning {
+			return run
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("A execução %s não terminou a tempo", runID)
+	return nil
+}
+
+func TestWorkflowEngineAsyncCancel(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	block := &blockStep{started: make(chan struct{}), release: make(chan struct{})}
+	err := engine.RegisterStepType("block", func(config models.StepConfig) (steps.StepExecutor, error) {
+		return block, nil
+	})
+	if err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+
+	workflow := &models.Workflow{
+		ID:   "async-workflow",
+		Name: "Async Workflow",
+		Steps: []models.Step{
+			{ID: "wait", Type: "block", Next: []models.Transition{{Step: "after"}}},
+			{ID: "after", Type: "echo", Config: map[string]interface{}{"message": "done"}},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	run, err := engine.StartWorkflow(workflow.ID, nil)
+	if err != nil {
+		t.Fatalf("Erro ao iniciar workflow: %v", err)
+	}
+	if run.Status != models.StatusRunning {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusRunning, run.Status)
+	}
+
+	<-block.started
+	if err := engine.CancelRun(run.ID); err != nil {
+		t.Fatalf("Erro ao cancelar execução: %v", err)
+	}
+	close(block.release)
+
+	run = waitRun(t, engine, run.ID)
+	if run.Status != models.StatusCancelled {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusCancelled, run.Status)
+	}
+	if status := run.Result.StepResults["after"].Status; status != models.StatusCancelled {
+		t.Errorf("Status esperado do step after %s, mas obteve %s", models.StatusCancelled, status)
+	}
+
+	if err := engine.CancelRun(run.ID); err == nil {
+		t.Error("Esperava erro ao cancelar execução finalizada")
+	}
+}
+
+func TestWorkflowEngineCancelSyncRun(t *testing.T) {
+	engine := NewWorkflowEngine()
+
+	block := &blockStep{started: make(chan struct{}), release: make(chan struct{})}
+	engine.RegisterStepType("block", func(config models.StepConfig) (steps.StepExecutor, error) {
+		return block, nil
+	})
+	workflow := &models.Workflow{
+		ID:    "sync-workflow",
+		Steps: []models.Step{{ID: "wait", Type: "block"}},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	done := make(chan *models.WorkflowResult)
+	go func() {
+		result, _ := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+		done <- result
+	}()
+
+	// Execuções síncronas também podem ser canceladas pelo ID
+	<-block.started
+	runs := engine.ListRuns(workflow.ID)
+	if len(runs) != 1 {
+		t.Fatalf("Esperava 1 execução, mas obteve %d", len(runs))
+	}
+	if err := engine.CancelRun(runs[0].ID); err != nil {
+		t.Fatalf("Erro ao cancelar execução síncrona: %v", err)
+	}
+	close(block.release)
+
+	if result := <-done; result.Status != models.StatusCancelled {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusCancelled, result.Status)
+	}
+}
+
+// panicStep entra em pânico ao ser executado
+type panicStep struct{}
+
+func (s *panicStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	panic("boom")
+}
+
+func TestWorkflowEngineStepPanic(t *testing.T) {
+	engine := NewWorkflowEngine()
+	err := engine.RegisterStepType("panic", func(config models.StepConfig) (steps.StepExecutor, error) {
+		return &panicStep{}, nil
+	})
+	if err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+
+	workflow := &models.Workflow{
+		ID: "panic-workflow",
+		Steps: []models.Step{{
+			ID:       "explode",
+			Type:     "panic",
+			Required: true,
+			Retry:    &models.RetryConfig{MaxAttempts: 3, Delay: time.Millisecond},
+		}},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	// O pânico falha o step sem retentativas, tanto em execuções síncronas quanto assíncronas
+	result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err == nil || !strings.Contains(err.Error(), "step panicked: boom") {
+		t.Fatalf("Esperava erro de pânico, mas obteve %v", err)
+	}
+	if stepResult := result.StepResults["explode"]; stepResult.Status != models.StatusFailed || stepResult.Attempts != 1 {
+		t.Errorf("Esperava o step falho após 1 tentativa: %+v", stepResult)
+	}
+
+	run, err := engine.StartWorkflow(workflow.ID, nil)
+	if err != nil {
+		t.Fatalf("Erro ao iniciar workflow: %v", err)
+	}
+	if run = waitRun(t, engine, run.ID); run.Status != models.StatusFailed {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusFailed, run.Status)
+	}
+}
+
+// sleepStep espera a duração configurada ou o cancelamento do contexto
+type sleepStep struct {
+	duration time.Duration
+}
+
+func (s *sleepStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.duration):
+		return &models.StepResult{Status: models.StatusCompleted}, nil
+	}
+}
+
+func TestWorkflowEngineTimeouts(t *testing.T) {
+	engine := NewWorkflowEngine()
+	err := engine.RegisterStepType("sleep", func(config models.StepConfig) (steps.StepExecutor, error) {
+		duration, err := time.ParseDuration(config["duration"].(string))
+		return &sleepStep{duration: duration}, err
+	})
+	if err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+
+	// Timeout de step
+	stepTimeout := &models.Workflow{
+		ID:   "step-timeout",
+		Name: "Step Timeout",
+		Steps: []models.Step{
+			{ID: "slow", Type: "sleep", Config: map[string]interface{}{"duration": "1s"}, Timeout: 20 * time.Millisecond},
+		},
+	}
+	if err := engine.RegisterWorkflow(stepTimeout); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), stepTimeout.ID)
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+	if status := result.StepResults["slow"].Status; status != models.StatusTimedOut {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusTimedOut, status)
+	}
+
+	// Timeout do workflow
+	workflowTimeout := &models.Workflow{
+		ID:      "workflow-timeout",
+		Name:    "Workflow Timeout",
+		Timeout: 20 * time.Millisecond,
+		Steps: []models.Step{
+			{ID: "slow", Type: "sleep", Config: map[string]interface{}{"duration": "1s"}, Next: []models.Transition{{Step: "after"}}},
+			{ID: "after", Type: "echo", Config: map[string]interface{}{"message": "done"}},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflowTimeout); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err = engine.ExecuteWorkflow(context.Background(), workflowTimeout.ID)
+	if err == nil {
+		t.Fatal("Esperava erro ao exceder o timeout do workflow")
+	}
+	if result.Status != models.StatusTimedOut {
+		t.Errorf("Status esperado %s, mas obteve %s", models.StatusTimedOut, result.Status)
+	}
+	if status := result.StepResults["slow"].Status; status != models.StatusTimedOut {
+		t.Errorf("Status esperado do step slow %s, mas obteve %s", models.StatusTimedOut, status)
+	}
+	if status := result.StepResults["after"].Status; status != models.StatusCancelled {
+		t.Errorf("Status esperado do step after %s, mas obteve %s", models.StatusCancelled, status)
+	}
+}
+
+func TestWorkflowEngineStore(t *testing.T) {
+	workflowStore := store.NewMemoryStore()
+	engine := NewWorkflowEngine(WithStore(workflowStore))
+
+	workflow := &models.Workflow{
+		ID:   "stored-workflow",
+		Name: "Stored Workflow",
+		Steps: []models.Step{
+			{ID: "step1", Type: "echo", Config: map[string]interface{}{"message": "hi"}},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	result, err := engine.ExecuteWorkflow(context.Background(), workflow.ID)
+	if err != nil {
+		t.Fatalf("Erro ao executar workflow: %v", err)
+	}
+
+	// Um novo engine sobre o mesmo store recupera workflows e execuções
+	restored := NewWorkflowEngine(WithStore(workflowStore))
+	if err := restored.Load(); err != nil {
+		t.Fatalf("Erro ao carregar store: %v", err)
+	}
+
+	if _, exists := restored.GetWorkflow(workflow.ID); !exists {
+		t.Error("Workflow não foi recuperado do store")
+	}
+
+	run, exists := restored.GetRun(result.RunID)
+	if !exists {
+		t.Fatalf("Execução %s não foi recuperada do store", result.RunID)
+	}
+	if run.Result.StepResults["step1"].Data != "hi" {
+		t.Errorf("Resultado inesperado: %v", run.Result.StepResults)
+	}
+
+	// Workflows registrados novamente recebem uma nova versão
+	if err := restored.DeleteWorkflow(workflow.ID); err != nil {
+		t.Fatalf("Erro ao remover workflow: %v", err)
+	}
+	if err := restored.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+	if workflow.Version != 2 {
+		t.Errorf("Versão esperada 2, mas obteve %d", workflow.Version)
+	}
+}
+
+// countStep conta quantas vezes foi executado
+type countStep struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countStep) factory(config models.StepConfig) (steps.StepExecutor, error) {
+	return countExecutor{step: s, id: config["id"].(string)}, nil
+}
+
+type countExecutor struct {
+	step *countStep
+	id   string
+}
+
+func (e countExecutor) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	e.step.mu.Lock()
+	defer e.step.mu.Unlock()
+
+	e.step.calls[e.id]++
+	return &models.StepResult{Status: models.StatusCompleted, Data: e.id}, nil
+}
+
+func TestWorkflowEngineResume(t *testing.T) {
+	workflowStore := store.NewMemoryStore()
+	counter := &countStep{calls: make(map[string]int)}
+
+	workflow := &models.Workflow{
+		ID:      "resume-workflow",
+		Name:    "Resume Workflow",
+		Version: 1,
+		Steps: []models.Step{
+			{ID: "create", Type: "count", Config: map[string]interface{}{"id": "create"}, Next: []models.Transition{{Step: "notify"}}},
+			{ID: "notify", Type: "count", Config: map[string]interface{}{"id": "notify"}},
+		},
+	}
+	if err := workflowStore.SaveWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao salvar workflow: %v", err)
+	}
+
+	// Simula uma execução interrompida depois do checkpoint do primeiro step
+	now := time.Now()
+	interrupted := &models.Run{
+		ID:              "interrupted-run",
+		WorkflowID:      workflow.ID,
+		WorkflowVersion: 1,
+		Status:          models.StatusRunning,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Result: &models.WorkflowResult{
+			WorkflowID: workflow.ID,
+			RunID:      "interrupted-run",
+			Status:     models.StatusRunning,
+			StartTime:  now,
+			StepResults: map[string]models.StepResult{
+				"create": {StepID: "create", Status: models.StatusCompleted, Data: "create"},
+			},
+		},
+	}
+	if err := workflowStore.SaveRun(interrupted); err != nil {
+		t.Fatalf("Erro ao salvar execução: %v", err)
+	}
+
+	engine := NewWorkflowEngine(WithStore(workflowStore))
+	if err := engine.RegisterStepType("count", counter.factory); err != nil {
+		t.Fatalf("Erro ao registrar tipo de step: %v", err)
+	}
+	if err := engine.Load(); err != nil {
+		t.Fatalf("Erro ao carregar store: %v", err)
+	}
+
+	resumed := engine.ResumeRuns()
+	if len(resumed) != 1 {
+		t.Fatalf("Esperava 1 execução retomada, mas obteve %d", len(resumed))
+	}
+
+	run := waitRun(t, engine, interrupted.ID)
+	if run.Status != models.StatusCompleted {
+		t.Errorf("Status esperado %s, mas obteve %s: %s", models.StatusCompleted, run.Status, run.Result.Error)
+	}
+
+	counter.mu.Lock()
+	defer counter.mu.Unlock()
+	if counter.calls["create"] != 0 || counter.calls["notify"] != 1 {
+		t.Errorf("Esperava apenas notify executado, chamadas: %v", counter.calls)
+	}
+
+	// O estado final também é persistido
+	stored, err := workflowStore.GetRun(interrupted.ID)
+	if err != nil || stored.Status != models.StatusCompleted {
+		t.Errorf("Execução persistida inesperada: %+v (%v)", stored, err)
+	}
+}
+
+func TestWorkflowEngineSchedule(t *testing.T) {
+	engine := NewWorkflowEngine()
+	defer engine.Close()
+
+	invalid := &models.Workflow{
+		ID:       "invalid-schedule",
+		Steps:    []models.Step{{ID: "hello", Type: "echo", Config: map[string]interface{}{"message": "hi"}}},
+		Schedule: &models.Schedule{Cron: "every day"},
+	}
+	if err := engine.RegisterWorkflow(invalid); err == nil {
+		t.Error("Esperava erro ao registrar workflow com agendamento inválido")
+	}
+
+	workflow := &models.Workflow{
+		ID:       "scheduled",
+		Steps:    []models.Step{{ID: "hello", Type: "echo", Config: map[string]interface{}{"message": "hi"}}},
+		Schedule: &models.Schedule{Interval: time.Hour},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+
+	status, exists := engine.GetSchedule(workflow.ID, 3)
+	if !exists || len(status.Upcoming) != 3 {
+		t.Fatalf("Esperava 3 próximos disparos, mas obteve %+v", status)
+	}
+
+	if err := engine.PauseSchedule(workflow.ID); err != nil {
+		t.Fatalf("Erro ao pausar agendamento: %v", err)
+	}
+	stored, err := engine.store.GetWorkflow(workflow.ID)
+	if err != nil || !stored.Schedule.Paused {
+		t.Errorf("Esperava agendamento pausado no armazenamento, mas obteve %+v (%v)", stored, err)
+	}
+	if status, _ := engine.GetSchedule(workflow.ID, 3); !status.Paused || len(status.Upcoming) != 0 {
+		t.Errorf("Agendamento pausado não deveria ter próximos disparos: %+v", status)
+	}
+
+	// A pausa substitui o workflow registrado em vez de alterar quem já o lê
+	if registered, _ := engine.GetWorkflow(workflow.ID); !registered.Schedule.Paused || workflow.Schedule.Paused {
+		t.Errorf("Esperava uma cópia pausada do workflow: %+v", registered.Schedule)
+	}
+
+	if err := engine.ResumeSchedule(workflow.ID); err != nil {
+		t.Fatalf("Erro ao retomar agendamento: %v", err)
+	}
+
+	if err := engine.DeleteWorkflow(workflow.ID); err != nil {
+		t.Fatalf("Erro ao remover workflow: %v", err)
+	}
+	if _, exists := engine.GetSchedule(workflow.ID, 0); exists {
+		t.Error("O agendamento deveria ser removido junto com o workflow")
+	}
+}
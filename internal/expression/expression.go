@@ -0,0 +1,71 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package expression
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// programs caches compiled expressions by their source code
+var programs sync.Map
+
+// Compile parses an expression and returns its compiled program
+func Compile(code string) (*vm.Program, error) {
+	if program, ok := programs.Load(code); ok {
+		return program.(*vm.Program), nil
+	}
+
+	program, err := expr.Compile(code, functions...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", code, err)
+	}
+
+	programs.Store(code, program)
+	return program, nil
+}
+
+// Evaluate evaluates an expression against the given environment, usually the
+// data of the execution context (steps, inputs, metadata)
+func Evaluate(code string, env map[string]interface{}) (interface{}, error) {
+	program, err := Compile(code)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := run(program, env)
+	if err != nil {
+		return nil, fmt.Errorf("error evaluating %q: %w", code, err)
+	}
+	return result, nil
+}
+
+// run runs a compiled program. Panics raised while fetching fields are
+// returned as errors.
+func run(program *vm.Program, env map[string]interface{}) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%v", r)
+		}
+	}()
+	return expr.Run(program, env)
+}
+
+// EvaluateBool evaluates a condition. The expression must return a boolean.
+func EvaluateBool(code string, env map[string]interface{}) (bool, error) {
+	result, err := Evaluate(code, env)
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition %q must return a boolean, got %T", code, result)
+	}
+	return value, nil
+}
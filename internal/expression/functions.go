@@ -0,0 +1,55 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package expression
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/expr-lang/expr"
+)
+
+// functions are the helpers available to expressions in addition to the
+// builtins of expr (upper, lower, trim, split, join, replace, now, date,
+// duration, abs, round, min, max, toJSON, fromJSON, ...)
+var functions = []expr.Option{
+	// default(value, fallback) returns fallback when value is nil or empty
+	expr.Function("default", func(params ...interface{}) (interface{}, error) {
+		if params[0] == nil || params[0] == "" {
+			return params[1], nil
+		}
+		return params[0], nil
+	}, new(func(interface{}, interface{}) interface{})),
+
+	// formatDate(date, layout) formats a date using a Go layout, e.g. "2006-01-02"
+	expr.Function("formatDate", func(params ...interface{}) (interface{}, error) {
+		date, ok := params[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("formatDate expects a date, got %T", params[0])
+		}
+		return date.Format(params[1].(string)), nil
+	}, new(func(interface{}, string) string)),
+
+	// urlEncode(s) escapes a string to be used in a URL query
+	expr.Function("urlEncode", func(params ...interface{}) (interface{}, error) {
+		return url.QueryEscape(params[0].(string)), nil
+	}, new(func(string) string)),
+
+	// toBase64(s) encodes a string with standard base64
+	expr.Function("toBase64", func(params ...interface{}) (interface{}, error) {
+		return base64.StdEncoding.EncodeToString([]byte(params[0].(string))), nil
+	}, new(func(string) string)),
+
+	// fromBase64(s) decodes a standard base64 string
+	expr.Function("fromBase64", func(params ...interface{}) (interface{}, error) {
+		data, err := base64.StdEncoding.DecodeString(params[0].(string))
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}, new(func(string) string)),
+}
@@ -0,0 +1,184 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package expression
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrUnresolved is returned when a placeholder evaluates to nothing, usually
+// because it references a step, input or variable that does not exist
+var ErrUnresolved = errors.New("unresolved reference")
+
+const (
+	openDelim  = "{{"
+	closeDelim = "}}"
+)
+
+// Render evaluates the {{ expression }} placeholders of a template. A template
+// made of a single placeholder returns the value of the expression as it is,
+// keeping its type; otherwise the values are formatted and concatenated.
+// Strings without placeholders are returned unchanged.
+func Render(template string, env map[string]interface{}) (interface{}, error) {
+	if !strings.Contains(template, openDelim) {
+		return template, nil
+	}
+
+	var out strings.Builder
+	rest := template
+	for {
+		start := strings.Index(rest, openDelim)
+		if start < 0 {
+			out.WriteString(rest)
+			break
+		}
+		end := strings.Index(rest[start:], closeDelim)
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in %q", template)
+		}
+		end += start
+
+		code := strings.TrimSpace(rest[start+len(openDelim) : end])
+		value, err := evaluatePlaceholder(code, env)
+		if err != nil {
+			return nil, err
+		}
+
+		// Um único placeholder preserva o tipo do valor
+		if start == 0 && end+len(closeDelim) == len(rest) && out.Len() == 0 {
+			return value, nil
+		}
+
+		out.WriteString(rest[:start])
+		out.WriteString(formatValue(value))
+		rest = rest[end+len(closeDelim):]
+	}
+	return out.String(), nil
+}
+
+// RenderString renders a template like Render, but always returns a string:
+// the value of a single placeholder is formatted as it would be when embedded
+// in a longer template.
+func RenderString(template string, env map[string]interface{}) (string, error) {
+	value, err := Render(template, env)
+	if err != nil {
+		return "", err
+	}
+	return formatValue(value), nil
+}
+
+// RenderValue renders every string found in a value, descending into maps and
+// slices. Errors name the path of the offending field, e.g. "headers.Authorization".
+func RenderValue(value interface{}, env map[string]interface{}) (interface{}, error) {
+	return renderValue(value, env, "")
+}
+
+func renderValue(value interface{}, env map[string]interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		rendered, err := Render(v, env)
+		if err != nil {
+			return nil, &FieldError{Field: path, Err: err}
+		}
+		return rendered, nil
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		rendered := make(map[string]interface{}, len(v))
+		for _, key := range keys {
+			item, err := renderValue(v[key], env, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = item
+		}
+		return rendered, nil
+
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			item, err := renderValue(item, env, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = item
+		}
+		return rendered, nil
+
+	default:
+		return value, nil
+	}
+}
+
+// FieldError is returned by RenderValue when a field cannot be rendered
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+// Error implements the error interface
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s: %v", e.Field, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// evaluatePlaceholder evaluates the expression of a placeholder. Expressions
+// that evaluate to nil, or fail to fetch a missing field, are unresolved.
+func evaluatePlaceholder(code string, env map[string]interface{}) (interface{}, error) {
+	if code == "" {
+		return nil, fmt.Errorf("empty placeholder")
+	}
+
+	program, err := Compile(code)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := run(program, env)
+	if err != nil {
+		return nil, fmt.Errorf("%w {{ %s }}: %v", ErrUnresolved, code, err)
+	}
+	if value == nil {
+		return nil, fmt.Errorf("%w {{ %s }}", ErrUnresolved, code)
+	}
+	return value, nil
+}
+
+// formatValue formats a value to be embedded in a string. Maps and slices are
+// encoded as JSON.
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
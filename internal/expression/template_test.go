@@ -0,0 +1,103 @@
+package expression
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	env := map[string]interface{}{
+		"steps": map[string]interface{}{
+			"login": map[string]interface{}{"data": map[string]interface{}{"token": "abc", "ttl": 60.0}},
+		},
+		"inputs": map[string]interface{}{"city": "João Pessoa", "tags": []interface{}{"a", "b"}},
+	}
+
+	tests := []struct {
+		template string
+		expected interface{}
+	}{
+		{"no placeholders", "no placeholders"},
+		{"Bearer {{ steps.login.data.token }}", "Bearer abc"},
+		{"{{ steps.login.data.ttl }}", 60.0},
+		{"{{ inputs.tags }}", []interface{}{"a", "b"}},
+		{"tags={{ inputs.tags }}", `tags=["a","b"]`},
+		{"{{ upper(inputs.city) }}", "JOÃO PESSOA"},
+		{"q={{ urlEncode(inputs.city) }}", "q=Jo%C3%A3o+Pessoa"},
+		{"{{ default(inputs.missing, 'fallback') }}", "fallback"},
+		{"{{ steps.login.data.ttl * 2 }}s", "120s"},
+		{"{{ toBase64('user:pass') }}", "dXNlcjpwYXNz"},
+		{"{{ formatDate(date('2024-03-01'), '02/01/2006') }}", "01/03/2024"},
+	}
+
+	for _, tt := range tests {
+		got, err := Render(tt.template, env)
+		if err != nil {
+			t.Errorf("Erro ao renderizar %q: %v", tt.template, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Render(%q) = %#v, esperava %#v", tt.template, got, tt.expected)
+		}
+	}
+}
+
+func TestRenderUnresolved(t *testing.T) {
+	env := map[string]interface{}{"inputs": map[string]interface{}{}}
+
+	for _, template := range []string{"{{ inputs.city }}", "x {{ steps.login.data.token }}"} {
+		if _, err := Render(template, env); !errors.Is(err, ErrUnresolved) {
+			t.Errorf("Esperava ErrUnresolved para %q, mas obteve %v", template, err)
+		}
+	}
+
+	if _, err := Render("{{ inputs.city", env); err == nil {
+		t.Error("Esperava erro para placeholder não fechado")
+	}
+}
+
+func TestRenderValue(t *testing.T) {
+	env := map[string]interface{}{"inputs": map[string]interface{}{"id": 7.0}}
+
+	value := map[string]interface{}{
+		"url":     "https://api.example.com/items/{{ inputs.id }}",
+		"body":    map[string]interface{}{"ids": []interface{}{"{{ inputs.id }}"}},
+		"headers": map[string]interface{}{"Authorization": "Bearer {{ secrets.token }}"},
+	}
+
+	_, err := RenderValue(value, env)
+	var fieldErr *FieldError
+	if !errors.As(err, &fieldErr) || fieldErr.Field != "headers.Authorization" {
+		t.Fatalf("Esperava erro no campo headers.Authorization, mas obteve %v", err)
+	}
+
+	delete(value, "headers")
+	rendered, err := RenderValue(value, env)
+	if err != nil {
+		t.Fatalf("Erro ao renderizar: %v", err)
+	}
+	expected := map[string]interface{}{
+		"url":  "https://api.example.com/items/7",
+		"body": map[string]interface{}{"ids": []interface{}{7.0}},
+	}
+	if !reflect.DeepEqual(rendered, expected) {
+		t.Errorf("Esperava %v, mas obteve %v", expected, rendered)
+	}
+}
+
+func TestRenderString(t *testing.T) {
+	env := map[string]interface{}{"n": 5, "user": map[string]interface{}{"id": 1}}
+	tests := map[string]string{
+		"{{ n }}":      "5",
+		"{{ user }}":   `{"id":1}`,
+		"n = {{ n }}":  "n = 5",
+		"sem template": "sem template",
+	}
+	for template, expected := range tests {
+		got, err := RenderString(template, env)
+		if err != nil || got != expected {
+			t.Errorf("RenderString(%q) = %q, %v; esperava %q", template, got, err, expected)
+		}
+	}
+}
@@ -5,7 +5,11 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
+
+	"github.com/carloskvasir/goflow/internal/connectors"
 )
 
 // WorkflowStatus represents the current state of a workflow
@@ -16,31 +20,131 @@ const (
 	StatusRunning   WorkflowStatus = "running"
 	StatusCompleted WorkflowStatus = "completed"
 	StatusFailed    WorkflowStatus = "failed"
+	StatusCancelled WorkflowStatus = "cancelled"
+	StatusTimedOut  WorkflowStatus = "timed_out"
+	StatusSkipped   WorkflowStatus = "skipped"
 )
 
 // Workflow represents a complete integration flow
 type Workflow struct {
-	ID          string        `json:"id"`
-	Name        string        `json:"name"`
-	Description string        `json:"description"`
-	Steps       []Step        `json:"steps"`
-	Status      WorkflowStatus `json:"status"`
-	CreatedAt   time.Time     `json:"created_at"`
-	UpdatedAt   time.Time     `json:"updated_at"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	ID             string                 `json:"id"`
+	Name           string                 `json:"name"`
+	Description    string                 `json:"description"`
+	Steps          []Step                 `json:"steps"`
+	Status         WorkflowStatus         `json:"status"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	Version        int                    `json:"version"`                   // Incremented every time a workflow with the same ID is registered
+	MaxConcurrency int                    `json:"max_concurrency,omitempty"` // Max steps running in parallel (0 means unlimited)
+	Timeout        time.Duration          `json:"timeout,omitempty"`         // Deadline for the whole run (0 means no deadline)
+	Schedule       *Schedule              `json:"schedule,omitempty"`        // Runs the workflow automatically
+	Webhook        *Webhook               `json:"webhook,omitempty"`         // Lets external systems start the workflow over HTTP
+	InputSchema    map[string]interface{} `json:"inputs,omitempty"`          // JSON Schema the inputs of every run must satisfy
+	Outputs        map[string]string      `json:"outputs,omitempty"`         // Output name -> JSON path in the execution context
+
+	// Connections declares named connections for the rest steps of the
+	// workflow; they take precedence over the global connections of the engine
+	Connections map[string]connectors.Config `json:"connections,omitempty"`
+}
+
+// Redacted returns a copy of the workflow safe to expose, e.g. in API
+// responses, without the credentials of its connections and the secret of its
+// webhook.
+func (w *Workflow) Redacted() *Workflow {
+	redacted := *w
+	if w.Connections != nil {
+		redacted.Connections = make(map[string]connectors.Config, len(w.Connections))
+		for name, config := range w.Connections {
+			redacted.Connections[name] = config.Redacted()
+		}
+	}
+	if w.Webhook != nil && w.Webhook.Secret != "" {
+		webhook := *w.Webhook
+		webhook.Secret = connectors.Redacted
+		redacted.Webhook = &webhook
+	}
+	return &redacted
+}
+
+// Webhook configures how inbound HTTP requests start a workflow. The request
+// body, headers and query become the inputs of the run.
+type Webhook struct {
+	Path             string `json:"path,omitempty"`               // Named path served at /hooks/<path>, in addition to /hooks/<workflow ID>
+	Secret           string `json:"secret,omitempty"`             // If set, requests must carry an HMAC-SHA256 signature of the body
+	SignatureHeader  string `json:"signature_header,omitempty"`   // Header carrying the signature (default X-Signature-256)
+	DeliveryIDHeader string `json:"delivery_id_header,omitempty"` // Header used to deduplicate deliveries (default X-Delivery-ID)
+	ResponseStep     string `json:"response_step,omitempty"`      // If set, the request waits for the run and responds with the data of this step
+}
+
+// OverlapPolicy defines what happens when a schedule fires while the previous
+// scheduled run is still running
+type OverlapPolicy string
+
+const (
+	OverlapSkip  OverlapPolicy = "skip"  // The new run is not started (default)
+	OverlapQueue OverlapPolicy = "queue" // The new run starts once the previous one finishes
+	OverlapAllow OverlapPolicy = "allow" // The new run starts right away
+)
+
+// Schedule configures when a workflow runs automatically. Exactly one of Cron
+// and Interval must be set.
+type Schedule struct {
+	Cron     string                 `json:"cron,omitempty"`     // Cron expression, e.g. "*/5 * * * *" or "@hourly"
+	Interval time.Duration          `json:"interval,omitempty"` // Fixed interval between runs
+	Timezone string                 `json:"timezone,omitempty"` // IANA timezone of the cron expression (default UTC)
+	Overlap  OverlapPolicy          `json:"overlap,omitempty"`
+	Inputs   map[string]interface{} `json:"inputs,omitempty"` // Inputs of the scheduled runs
+	Paused   bool                   `json:"paused,omitempty"`
 }
 
 // Step represents an individual step in the workflow
 type Step struct {
-	ID       string                 `json:"id"`
-	Name     string                 `json:"name"`
-	Type     string                 `json:"type"` // "rest", "soap", "graphql", "transform"
-	Config   StepConfig             `json:"config"`
-	Next     []string              `json:"next,omitempty"`    // IDs of next steps
-	OnError  []string              `json:"on_error,omitempty"` // IDs of steps to execute on error
-	Retry    *RetryConfig          `json:"retry,omitempty"`
-	Timeout  time.Duration         `json:"timeout,omitempty"`
-	Required bool                  `json:"required"` // If true, step failure fails the entire workflow
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	Type       string        `json:"type"` // "rest", "soap", "graphql", "transform"
+	Config     StepConfig    `json:"config"`
+	Next       []Transition  `json:"next,omitempty"`       // Next steps, optionally conditional
+	OnError    []string      `json:"on_error,omitempty"`   // IDs of steps to execute on error
+	Compensate string        `json:"compensate,omitempty"` // ID of the step that undoes this one if the workflow fails
+	Retry      *RetryConfig  `json:"retry,omitempty"`
+	Timeout    time.Duration `json:"timeout,omitempty"` // Deadline for each attempt of the step (0 means no deadline)
+	Required   bool          `json:"required"`          // If true, step failure fails the entire workflow
+}
+
+// Transition links a step to a next step. When is an optional condition
+// evaluated against the execution context, e.g. "steps.check.data.status == 'down'";
+// the next step only runs through this transition if it evaluates to true.
+type Transition struct {
+	Step string `json:"step"`
+	When string `json:"when,omitempty"`
+}
+
+// UnmarshalJSON accepts either a step ID or a transition object
+func (t *Transition) UnmarshalJSON(data []byte) error {
+	var stepID string
+	if err := json.Unmarshal(data, &stepID); err == nil {
+		*t = Transition{Step: stepID}
+		return nil
+	}
+
+	type transition Transition
+	var decoded transition
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("transition must be a step ID or an object: %w", err)
+	}
+	*t = Transition(decoded)
+	return nil
+}
+
+// MarshalJSON encodes unconditional transitions as plain step IDs
+func (t Transition) MarshalJSON() ([]byte, error) {
+	if t.When == "" {
+		return json.Marshal(t.Step)
+	}
+
+	type transition Transition
+	return json.Marshal(transition(t))
 }
 
 // StepConfig represents the configuration for a step
@@ -48,30 +152,60 @@ type StepConfig map[string]interface{}
 
 // RetryConfig configures retry attempts for a step
 type RetryConfig struct {
-	MaxAttempts int           `json:"max_attempts"`
-	Delay       time.Duration `json:"delay"`
-	MaxDelay    time.Duration `json:"max_delay"`
-	Multiplier  float64       `json:"multiplier"`
+	MaxAttempts   int           `json:"max_attempts"`
+	Delay         time.Duration `json:"delay"`
+	MaxDelay      time.Duration `json:"max_delay"`
+	Multiplier    float64       `json:"multiplier"`
+	Jitter        float64       `json:"jitter,omitempty"`          // Fraction of each delay randomized, between 0 and 1
+	RetryOnStatus []int         `json:"retry_on_status,omitempty"` // HTTP status codes retried (default 408, 425, 429, 500, 502, 503, 504)
+	RetryOn       []string      `json:"retry_on,omitempty"`        // Error classes retried: "status", "timeout", "connection", "error" (default all but "error")
 }
 
 // WorkflowResult represents the result of a workflow execution
 type WorkflowResult struct {
-	WorkflowID  string                 `json:"workflow_id"`
-	Status      WorkflowStatus         `json:"status"`
-	StepResults map[string]StepResult  `json:"step_results"`
+	WorkflowID  string                `json:"workflow_id"`
+	RunID       string                `json:"run_id"`
+	Status      WorkflowStatus        `json:"status"`
+	StepResults map[string]StepResult `json:"step_results"`
 	StartTime   time.Time             `json:"start_time"`
 	EndTime     time.Time             `json:"end_time"`
 	Error       string                `json:"error,omitempty"`
+
+	// Outputs holds the values of the outputs declared by the workflow
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+
+	// Compensations lists the compensation steps run after a failure, in the
+	// order they were run
+	Compensations []CompensationResult `json:"compensations,omitempty"`
+}
+
+// CompensationResult represents the execution of the compensation of a step
+type CompensationResult struct {
+	StepID string     `json:"step_id"` // Step being compensated
+	Result StepResult `json:"result"`
 }
 
 // StepResult represents the result of a step execution
 type StepResult struct {
-	StepID      string                 `json:"step_id"`
-	Status      WorkflowStatus         `json:"status"`
-	StartTime   time.Time             `json:"start_time"`
-	EndTime     time.Time             `json:"end_time"`
-	Data        interface{}           `json:"data,omitempty"`
-	Error       string                `json:"error,omitempty"`
-	Attempts    int                   `json:"attempts"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	StepID    string                 `json:"step_id"`
+	Status    WorkflowStatus         `json:"status"`
+	StartTime time.Time              `json:"start_time"`
+	EndTime   time.Time              `json:"end_time"`
+	Data      interface{}            `json:"data,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+	Attempts  int                    `json:"attempts"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Run represents a single execution of a workflow
+type Run struct {
+	ID              string                 `json:"id"`
+	WorkflowID      string                 `json:"workflow_id"`
+	WorkflowVersion int                    `json:"workflow_version"`
+	ParentRunID     string                 `json:"parent_run_id,omitempty"` // Run that invoked this one through a workflow step
+	Inputs          map[string]interface{} `json:"inputs,omitempty"`
+	Status          WorkflowStatus         `json:"status"`
+	Result          *WorkflowResult        `json:"result"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
 }
@@ -0,0 +1,289 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+// Package retry implements the retry policy shared by the workflow engine and
+// the connectors: which errors are retried, how long to wait between attempts
+// and how each attempt went.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Error classes that a policy can retry
+const (
+	ClassStatus     = "status"     // HTTP responses with a retryable status code
+	ClassTimeout    = "timeout"    // Attempts that timed out
+	ClassConnection = "connection" // Connections refused, reset or closed early
+	ClassError      = "error"      // Any other error not marked as permanent
+)
+
+// DefaultRetryableStatus lists the status codes retried when a policy sets none
+var DefaultRetryableStatus = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooEarly,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultClasses lists the error classes retried when a policy sets none. Other
+// errors, such as invalid data, would usually fail every attempt the same way,
+// so ClassError must be set explicitly to retry them.
+var DefaultClasses = []string{ClassStatus, ClassTimeout, ClassConnection}
+
+// Policy configures how an operation is retried. The wait before the attempt
+// n+1 is Delay * Multiplier^(n-1), capped at MaxDelay and reduced by up to
+// Jitter (a fraction between 0 and 1) at random. A Retry-After given by the
+// server is honored if it is longer; if it is longer than MaxDelay the
+// operation is not retried at all.
+type Policy struct {
+	MaxAttempts     int
+	Delay           time.Duration
+	MaxDelay        time.Duration
+	Multiplier      float64
+	Jitter          float64
+	RetryableStatus []int    // Status codes retried (default DefaultRetryableStatus)
+	Classes         []string // Error classes retried (default DefaultClasses)
+}
+
+// Attempt records the outcome of one attempt
+type Attempt struct {
+	Attempt    int           `json:"attempt"`
+	Error      string        `json:"error,omitempty"`
+	Class      string        `json:"class,omitempty"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Wait       time.Duration `json:"wait,omitempty"` // Time waited before the next attempt
+}
+
+// StatusError is returned when an HTTP request completes with an unsuccessful
+// status code
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+	RetryAfter time.Duration // Wait requested by the server in the Retry-After header
+}
+
+// Error implements the error interface
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// NewStatusError creates the error of an HTTP response, reading the wait
+// requested by the server from its Retry-After header value
+func NewStatusError(statusCode int, body []byte, retryAfter string) *StatusError {
+	return &StatusError{
+		StatusCode: statusCode,
+		Body:       body,
+		RetryAfter: ParseRetryAfter(retryAfter, time.Now()),
+	}
+}
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not retryable, e.g. an invalid configuration
+// that would fail every attempt the same way
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Classify returns the class of an error, or an empty string if the error is
+// never retried: permanent errors and cancellations.
+func Classify(err error) string {
+	var permanent *permanentError
+	var statusErr *StatusError
+	var netErr net.Error
+
+	switch {
+	case err == nil, errors.As(err, &permanent), errors.Is(err, context.Canceled):
+		return ""
+	case errors.As(err, &statusErr):
+		return ClassStatus
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ClassTimeout
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return ClassConnection
+	default:
+		var opErr *net.OpError
+		if errors.As(err, &opErr) {
+			return ClassConnection
+		}
+		return ClassError
+	}
+}
+
+// Retryable reports whether the policy retries an error. Responses whose
+// Retry-After asks for a longer wait than MaxDelay are not retried.
+func (p Policy) Retryable(err error) bool {
+	class := Classify(err)
+	if class == "" || !containsString(p.classes(), class) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		if p.MaxDelay > 0 && statusErr.RetryAfter > p.MaxDelay {
+			return false
+		}
+		return p.RetryableStatusCode(statusErr.StatusCode)
+	}
+	return true
+}
+
+// RetryableStatusCode reports whether the policy retries a status code
+func (p Policy) RetryableStatusCode(statusCode int) bool {
+	if !containsString(p.classes(), ClassStatus) {
+		return false
+	}
+	status := p.RetryableStatus
+	if len(status) == 0 {
+		status = DefaultRetryableStatus
+	}
+	return contains(status, statusCode)
+}
+
+// Backoff returns how long to wait after a failed attempt, numbered from 1,
+// before the next one
+func (p Policy) Backoff(attempt int, err error) time.Duration {
+	delay := p.Delay
+	if p.Multiplier > 0 && attempt > 1 {
+		delay = time.Duration(float64(delay) * math.Pow(p.Multiplier, float64(attempt-1)))
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	// O servidor pode pedir uma espera maior que o backoff, mas não menor
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > delay {
+		delay = statusErr.RetryAfter
+	}
+	return delay
+}
+
+// Do calls fn until it succeeds, fails with an error the policy does not
+// retry, or runs out of attempts, waiting between attempts as long as ctx
+// allows. It returns the outcome of every attempt and the last error.
+func Do(ctx context.Context, policy Policy, fn func(ctx context.Context, attempt int) error) ([]Attempt, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+
+	var attempts []Attempt
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx, attempt)
+		record := Attempt{Attempt: attempt}
+		if err == nil {
+			return append(attempts, record), nil
+		}
+
+		record.Error = err.Error()
+		record.Class = Classify(err)
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) {
+			record.StatusCode = statusErr.StatusCode
+		}
+
+		// Não tenta de novo se o contexto da operação acabou
+		if attempt >= maxAttempts || ctx.Err() != nil || !policy.Retryable(err) {
+			return append(attempts, record), err
+		}
+
+		record.Wait = policy.Backoff(attempt, err)
+		attempts = append(attempts, record)
+		if waitErr := Sleep(ctx, record.Wait); waitErr != nil {
+			return attempts, err
+		}
+	}
+}
+
+// Sleep waits for the given duration or until ctx is done, returning the
+// context error in the latter case
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ParseRetryAfter parses the value of a Retry-After header, given either in
+// seconds or as an HTTP date. It returns 0 if the value is empty or invalid.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+func (p Policy) classes() []string {
+	if len(p.Classes) == 0 {
+		return DefaultClasses
+	}
+	return p.Classes
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
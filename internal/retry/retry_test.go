@@ -0,0 +1,147 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err   error
+		class string
+	}{
+		{nil, ""},
+		{Permanent(errors.New("invalid config")), ""},
+		{fmt.Errorf("step: %w", context.Canceled), ""},
+		{&StatusError{StatusCode: 503}, ClassStatus},
+		{fmt.Errorf("attempt: %w", context.DeadlineExceeded), ClassTimeout},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, ClassConnection},
+		{fmt.Errorf("read: %w", io.ErrUnexpectedEOF), ClassConnection},
+		{errors.New("something else"), ClassError},
+	}
+	for _, tt := range tests {
+		if class := Classify(tt.err); class != tt.class {
+			t.Errorf("Classify(%v) = %q, esperava %q", tt.err, class, tt.class)
+		}
+	}
+}
+
+func TestPolicyRetryable(t *testing.T) {
+	policy := Policy{}
+	if !policy.Retryable(&StatusError{StatusCode: 429}) || !policy.Retryable(&StatusError{StatusCode: 503}) {
+		t.Error("429 e 503 deveriam ser retentados por padrão")
+	}
+	if policy.Retryable(&StatusError{StatusCode: 404}) {
+		t.Error("404 não deveria ser retentado")
+	}
+	if !policy.Retryable(context.DeadlineExceeded) || policy.Retryable(errors.New("invalid data")) {
+		t.Error("Por padrão apenas timeouts, e não erros genéricos, deveriam ser retentados")
+	}
+	if !(Policy{Classes: []string{ClassError}}).Retryable(errors.New("invalid data")) {
+		t.Error("Erros genéricos deveriam ser retentados com a classe error")
+	}
+
+	policy = Policy{RetryableStatus: []int{404}, Classes: []string{ClassStatus}}
+	if !policy.Retryable(&StatusError{StatusCode: 404}) {
+		t.Error("404 deveria ser retentado quando configurado")
+	}
+	if policy.Retryable(errors.New("other")) || policy.Retryable(context.DeadlineExceeded) {
+		t.Error("Apenas a classe status deveria ser retentada")
+	}
+}
+
+func TestPolicyBackoff(t *testing.T) {
+	policy := Policy{Delay: 100 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}
+	for i, want := range expected {
+		if got := policy.Backoff(i+1, errors.New("x")); got != want {
+			t.Errorf("Backoff(%d) = %v, esperava %v", i+1, got, want)
+		}
+	}
+
+	// Retry-After maior que o backoff é respeitado integralmente
+	if got := policy.Backoff(1, &StatusError{StatusCode: 429, RetryAfter: 500 * time.Millisecond}); got != 500*time.Millisecond {
+		t.Errorf("Esperava 500ms de Retry-After, mas obteve %v", got)
+	}
+	if got := (Policy{Delay: time.Second}).Backoff(1, &StatusError{StatusCode: 429, RetryAfter: time.Minute}); got != time.Minute {
+		t.Errorf("Esperava 1m de Retry-After, mas obteve %v", got)
+	}
+
+	// Acima de MaxDelay a requisição não é retentada
+	if policy.Retryable(&StatusError{StatusCode: 429, RetryAfter: time.Minute}) {
+		t.Error("Retry-After acima de MaxDelay não deveria ser retentado")
+	}
+	attempts, err := Do(context.Background(), Policy{MaxAttempts: 3, MaxDelay: time.Second}, func(ctx context.Context, attempt int) error {
+		return &StatusError{StatusCode: 429, RetryAfter: time.Minute}
+	})
+	if err == nil || len(attempts) != 1 {
+		t.Errorf("Esperava desistir após 1 tentativa: %v %v", attempts, err)
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(1, nil); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("Backoff com jitter fora do intervalo: %v", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"3":                             3 * time.Second,
+		"-1":                            0,
+		"Mon, 01 Jan 2024 12:00:30 GMT": 30 * time.Second,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, expected := range tests {
+		if got := ParseRetryAfter(value, now); got != expected {
+			t.Errorf("ParseRetryAfter(%q) = %v, esperava %v", value, got, expected)
+		}
+	}
+}
+
+func TestDo(t *testing.T) {
+	policy := Policy{MaxAttempts: 4, Delay: time.Millisecond}
+
+	// Erros transitórios são retentados até o sucesso
+	attempts, err := Do(context.Background(), policy, func(ctx context.Context, attempt int) error {
+		if attempt < 3 {
+			return &StatusError{StatusCode: 503}
+		}
+		return nil
+	})
+	if err != nil || len(attempts) != 3 {
+		t.Fatalf("Esperava sucesso na 3ª tentativa, mas obteve %v %v", attempts, err)
+	}
+	if attempts[0].StatusCode != 503 || attempts[0].Class != ClassStatus || attempts[0].Wait != time.Millisecond || attempts[2].Error != "" {
+		t.Errorf("Tentativas registradas inesperadas: %+v", attempts)
+	}
+
+	// Erros permanentes encerram na primeira tentativa
+	attempts, err = Do(context.Background(), policy, func(ctx context.Context, attempt int) error {
+		return Permanent(errors.New("bad config"))
+	})
+	if err == nil || len(attempts) != 1 {
+		t.Errorf("Esperava 1 tentativa com erro permanente, mas obteve %v %v", attempts, err)
+	}
+
+	// A espera entre tentativas termina com o contexto
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	attempts, err = Do(ctx, Policy{MaxAttempts: 3, Delay: time.Hour}, func(ctx context.Context, attempt int) error {
+		return errors.New("unavailable")
+	})
+	if err == nil || len(attempts) != 1 || time.Since(start) > time.Second {
+		t.Errorf("Esperava desistir com o contexto, mas obteve %v %v após %v", attempts, err, time.Since(start))
+	}
+}
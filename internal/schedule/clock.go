@@ -0,0 +1,34 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package schedule
+
+import "time"
+
+// Clock is the source of time of the scheduler, so that tests can control it
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// After returns a channel that receives the current time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the Clock backed by the system time
+type realClock struct{}
+
+// RealClock returns the Clock backed by the system time
+func RealClock() Clock {
+	return realClock{}
+}
+
+// Now implements the Clock interface
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// After implements the Clock interface
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
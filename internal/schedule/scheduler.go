@@ -0,0 +1,354 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package schedule
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// QueuePollInterval is how often a schedule with queued runs checks whether
+// the previous run has finished
+const QueuePollInterval = time.Second
+
+// idleWait is how long the scheduler sleeps when no schedule is active
+const idleWait = time.Minute
+
+// Runner starts the scheduled runs and reports their status
+type Runner interface {
+	// StartWorkflow starts a run of a workflow in the background
+	StartWorkflow(workflowID string, inputs map[string]interface{}) (*models.Run, error)
+
+	// GetRun returns a run by its ID
+	GetRun(runID string) (*models.Run, bool)
+}
+
+// Status describes the state of the schedule of a workflow
+type Status struct {
+	WorkflowID string          `json:"workflow_id"`
+	Schedule   models.Schedule `json:"schedule"`
+	Paused     bool            `json:"paused"`
+	NextRun    *time.Time      `json:"next_run,omitempty"`
+	LastRunID  string          `json:"last_run_id,omitempty"`
+	Queued     int             `json:"queued"` // Runs waiting for the previous one to finish
+	Upcoming   []time.Time     `json:"upcoming,omitempty"`
+}
+
+// entry is the schedule of a single workflow
+type entry struct {
+	workflowID string
+	schedule   models.Schedule
+	spec       Spec
+	paused     bool
+	next       time.Time
+	lastRunID  string
+	queued     int
+}
+
+// Scheduler starts workflow runs according to their schedules
+type Scheduler struct {
+	runner  Runner
+	clock   Clock
+	entries map[string]*entry
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	mu      sync.Mutex
+}
+
+// NewScheduler creates a scheduler that starts runs through runner. Call Start
+// to begin firing schedules.
+func NewScheduler(runner Runner, clock Clock) *Scheduler {
+	if clock == nil {
+		clock = RealClock()
+	}
+	return &Scheduler{
+		runner:  runner,
+		clock:   clock,
+		entries: make(map[string]*entry),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Set creates or replaces the schedule of a workflow. A nil schedule removes it.
+func (s *Scheduler) Set(workflowID string, schedule *models.Schedule) error {
+	if schedule == nil {
+		s.Remove(workflowID)
+		return nil
+	}
+
+	spec, err := Parse(*schedule)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.entries[workflowID] = &entry{
+		workflowID: workflowID,
+		schedule:   *schedule,
+		spec:       spec,
+		paused:     schedule.Paused,
+		next:       spec.Next(s.clock.Now()),
+	}
+	s.mu.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Remove removes the schedule of a workflow
+func (s *Scheduler) Remove(workflowID string) {
+	s.mu.Lock()
+	delete(s.entries, workflowID)
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Pause stops firing the schedule of a workflow until it is resumed
+func (s *Scheduler) Pause(workflowID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[workflowID]
+	if !exists {
+		return fmt.Errorf("workflow %s has no schedule", workflowID)
+	}
+	e.paused = true
+	e.queued = 0
+	return nil
+}
+
+// Resume resumes a paused schedule. Fire times missed while it was paused are
+// not recovered.
+func (s *Scheduler) Resume(workflowID string) error {
+	s.mu.Lock()
+	e, exists := s.entries[workflowID]
+	if !exists {
+		s.mu.Unlock()
+		return fmt.Errorf("workflow %s has no schedule", workflowID)
+	}
+	if e.paused {
+		e.paused = false
+		e.next = e.spec.Next(s.clock.Now())
+	}
+	s.mu.Unlock()
+
+	s.notify()
+	return nil
+}
+
+// Status returns the state of the schedule of a workflow, including its next
+// upcoming fire times.
+func (s *Scheduler) Status(workflowID string, upcoming int) (*Status, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[workflowID]
+	if !exists {
+		return nil, false
+	}
+
+	status := e.status()
+	if !e.paused {
+		next := e.next
+		for i := 0; i < upcoming; i++ {
+			status.Upcoming = append(status.Upcoming, next)
+			next = e.spec.Next(next)
+		}
+	}
+	return status, true
+}
+
+// List returns the state of every schedule, ordered by workflow ID
+func (s *Scheduler) List() []*Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]*Status, 0, len(s.entries))
+	for _, e := range s.entries {
+		statuses = append(statuses, e.status())
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].WorkflowID < statuses[j].WorkflowID
+	})
+	return statuses
+}
+
+// Start begins firing schedules in the background
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(s.stop, s.done)
+}
+
+// Stop stops firing schedules and waits for the scheduler to finish. Runs
+// already started are not affected.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	stop, done := s.stop, s.done
+	s.stop, s.done = nil, nil
+	s.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// loop fires the due schedules and sleeps until the next fire time
+func (s *Scheduler) loop(stop, done chan struct{}) {
+	defer close(done)
+
+	for {
+		wait := s.tick()
+		select {
+		case <-s.clock.After(wait):
+		case <-s.wake:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// notify wakes the loop up so that it recomputes the next fire time
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// tick fires the schedules that are due and returns how long the scheduler
+// can sleep until the next fire time.
+func (s *Scheduler) tick() time.Duration {
+	now := s.clock.Now()
+
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.entries))
+	for id := range s.entries {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	sort.Strings(ids)
+	for _, id := range ids {
+		s.fire(id, now)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := idleWait
+	for _, e := range s.entries {
+		if e.paused {
+			continue
+		}
+		if until := e.next.Sub(now); until < wait {
+			wait = until
+		}
+		if e.queued > 0 && QueuePollInterval < wait {
+			wait = QueuePollInterval
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// fire starts a run of a workflow if its schedule is due, or if it has queued
+// runs, applying the overlap policy. The runner is called without holding s.mu.
+func (s *Scheduler) fire(workflowID string, now time.Time) {
+	s.mu.Lock()
+	e, exists := s.entries[workflowID]
+	if !exists || e.paused {
+		s.mu.Unlock()
+		return
+	}
+
+	due := !e.next.After(now)
+	if due {
+		// Disparos perdidos, por exemplo durante uma pausa longa, não são recuperados
+		e.next = e.spec.Next(now)
+	}
+	pending := e.queued
+	if due {
+		pending++
+	}
+	lastRunID := e.lastRunID
+	schedule := e.schedule
+	s.mu.Unlock()
+
+	if pending == 0 {
+		return
+	}
+
+	running := false
+	if lastRunID != "" {
+		run, exists := s.runner.GetRun(lastRunID)
+		running = exists && run.Status == models.StatusRunning
+	}
+
+	start := false
+	switch {
+	case !running || schedule.Overlap == models.OverlapAllow:
+		start = true
+		pending--
+	case schedule.Overlap == models.OverlapQueue:
+	default:
+		log.Printf("skipping scheduled run of workflow %s: run %s is still running", workflowID, lastRunID)
+		pending = 0
+	}
+
+	var run *models.Run
+	if start {
+		var err error
+		if run, err = s.runner.StartWorkflow(workflowID, schedule.Inputs); err != nil {
+			log.Printf("error starting scheduled run of workflow %s: %v", workflowID, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// O schedule pode ter sido substituído enquanto a execução era iniciada
+	if s.entries[workflowID] != e {
+		return
+	}
+	e.queued = pending
+	if run != nil {
+		e.lastRunID = run.ID
+	}
+}
+
+// status returns the state of the entry. The caller must hold s.mu.
+func (e *entry) status() *Status {
+	status := &Status{
+		WorkflowID: e.workflowID,
+		Schedule:   e.schedule,
+		Paused:     e.paused,
+		LastRunID:  e.lastRunID,
+		Queued:     e.queued,
+	}
+	status.Schedule.Paused = e.paused
+	if !e.paused {
+		next := e.next
+		status.NextRun = &next
+	}
+	return status
+}
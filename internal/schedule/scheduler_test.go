@@ -0,0 +1,211 @@
+package schedule
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// fakeClock é um relógio controlado pelo teste
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return make(chan time.Time)
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// fakeRunner registra as execuções iniciadas; elas ficam rodando até finish
+type fakeRunner struct {
+	mu   sync.Mutex
+	runs map[string]*models.Run
+	ids  []string
+}
+
+func newFakeRunner() *fakeRunner {
+	return &fakeRunner{runs: make(map[string]*models.Run)}
+}
+
+func (r *fakeRunner) StartWorkflow(workflowID string, inputs map[string]interface{}) (*models.Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run := &models.Run{ID: fmt.Sprintf("run-%d", len(r.ids)+1), WorkflowID: workflowID, Status: models.StatusRunning}
+	r.runs[run.ID] = run
+	r.ids = append(r.ids, run.ID)
+	return run, nil
+}
+
+func (r *fakeRunner) GetRun(runID string) (*models.Run, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, exists := r.runs[runID]
+	if !exists {
+		return nil, false
+	}
+	runCopy := *run
+	return &runCopy, true
+}
+
+func (r *fakeRunner) finish(runID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs[runID].Status = models.StatusCompleted
+}
+
+func (r *fakeRunner) started() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.ids)
+}
+
+func newTestScheduler(t *testing.T, schedule models.Schedule) (*Scheduler, *fakeClock, *fakeRunner) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)}
+	runner := newFakeRunner()
+	scheduler := NewScheduler(runner, clock)
+	if err := scheduler.Set("wf", &schedule); err != nil {
+		t.Fatalf("Erro ao configurar schedule: %v", err)
+	}
+	return scheduler, clock, runner
+}
+
+func TestSchedulerInterval(t *testing.T) {
+	scheduler, clock, runner := newTestScheduler(t, models.Schedule{Interval: time.Minute})
+
+	if wait := scheduler.tick(); wait != time.Minute {
+		t.Errorf("Esperava espera de 1m, mas obteve %v", wait)
+	}
+	if runner.started() != 0 {
+		t.Fatal("Nenhuma execução deveria ter sido iniciada antes do primeiro disparo")
+	}
+
+	clock.Advance(time.Minute)
+	scheduler.tick()
+	if runner.started() != 1 {
+		t.Fatalf("Esperava 1 execução, mas obteve %d", runner.started())
+	}
+}
+
+func TestSchedulerOverlapPolicies(t *testing.T) {
+	tests := []struct {
+		overlap  models.OverlapPolicy
+		expected [2]int // execuções iniciadas após o segundo disparo e após o término da primeira
+	}{
+		{overlap: models.OverlapSkip, expected: [2]int{1, 1}},
+		{overlap: models.OverlapQueue, expected: [2]int{1, 2}},
+		{overlap: models.OverlapAllow, expected: [2]int{2, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.overlap), func(t *testing.T) {
+			scheduler, clock, runner := newTestScheduler(t, models.Schedule{Interval: time.Minute, Overlap: tt.overlap})
+
+			clock.Advance(time.Minute)
+			scheduler.tick()
+			clock.Advance(time.Minute)
+			scheduler.tick()
+			var got [2]int
+			got[0] = runner.started()
+
+			runner.finish("run-1")
+			clock.Advance(QueuePollInterval)
+			scheduler.tick()
+			got[1] = runner.started()
+
+			if got != tt.expected {
+				t.Errorf("Esperava %v execuções, mas obteve %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSchedulerCronTimezone(t *testing.T) {
+	scheduler, _, _ := newTestScheduler(t, models.Schedule{Cron: "0 9 * * *", Timezone: "America/Sao_Paulo"})
+
+	status, exists := scheduler.Status("wf", 2)
+	if !exists {
+		t.Fatal("Schedule não encontrado")
+	}
+
+	// 9h em São Paulo são 12h em UTC
+	expected := []time.Time{
+		time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+	}
+	if len(status.Upcoming) != len(expected) {
+		t.Fatalf("Esperava %d disparos, mas obteve %v", len(expected), status.Upcoming)
+	}
+	for i := range expected {
+		if !status.Upcoming[i].Equal(expected[i]) {
+			t.Errorf("Esperava disparo em %v, mas obteve %v", expected[i], status.Upcoming[i])
+		}
+	}
+}
+
+func TestSchedulerPauseResume(t *testing.T) {
+	scheduler, clock, runner := newTestScheduler(t, models.Schedule{Interval: time.Minute})
+
+	if err := scheduler.Pause("wf"); err != nil {
+		t.Fatalf("Erro ao pausar schedule: %v", err)
+	}
+	clock.Advance(5 * time.Minute)
+	scheduler.tick()
+	if runner.started() != 0 {
+		t.Fatal("Schedule pausado não deveria iniciar execuções")
+	}
+
+	// Disparos perdidos durante a pausa não são recuperados
+	if err := scheduler.Resume("wf"); err != nil {
+		t.Fatalf("Erro ao retomar schedule: %v", err)
+	}
+	scheduler.tick()
+	if runner.started() != 0 {
+		t.Fatal("Disparos perdidos durante a pausa não deveriam ser executados")
+	}
+	clock.Advance(time.Minute)
+	scheduler.tick()
+	if runner.started() != 1 {
+		t.Errorf("Esperava 1 execução após retomar, mas obteve %d", runner.started())
+	}
+
+	if err := scheduler.Pause("missing"); err == nil {
+		t.Error("Esperava erro ao pausar schedule inexistente")
+	}
+}
+
+func TestParse(t *testing.T) {
+	invalid := []models.Schedule{
+		{},
+		{Cron: "* * * * *", Interval: time.Minute},
+		{Interval: -time.Minute},
+		{Cron: "not a cron"},
+		{Cron: "* * * * *", Timezone: "Nowhere/Nothing"},
+		{Interval: time.Minute, Overlap: "sometimes"},
+	}
+	for _, schedule := range invalid {
+		if _, err := Parse(schedule); err == nil {
+			t.Errorf("Esperava erro para o schedule %+v", schedule)
+		}
+	}
+
+	if _, err := Parse(models.Schedule{Cron: "@hourly", Overlap: models.OverlapQueue}); err != nil {
+		t.Errorf("Erro inesperado: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// Spec computes the fire times of a schedule
+type Spec interface {
+	// Next returns the first fire time after t
+	Next(t time.Time) time.Time
+}
+
+// intervalSpec fires at a fixed interval
+type intervalSpec struct {
+	interval time.Duration
+}
+
+// Next implements the Spec interface
+func (s intervalSpec) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// Parse validates a schedule and returns the Spec that computes its fire times
+func Parse(schedule models.Schedule) (Spec, error) {
+	switch schedule.Overlap {
+	case "", models.OverlapSkip, models.OverlapQueue, models.OverlapAllow:
+	default:
+		return nil, fmt.Errorf("unknown overlap policy: %s", schedule.Overlap)
+	}
+
+	switch {
+	case schedule.Cron != "" && schedule.Interval != 0:
+		return nil, fmt.Errorf("cron and interval cannot be used together")
+	case schedule.Interval < 0:
+		return nil, fmt.Errorf("interval must be positive")
+	case schedule.Interval > 0:
+		return intervalSpec{interval: schedule.Interval}, nil
+	case schedule.Cron == "":
+		return nil, fmt.Errorf("either cron or interval is required")
+	}
+
+	location := time.UTC
+	if schedule.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(schedule.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %s: %w", schedule.Timezone, err)
+		}
+	}
+
+	spec, err := cron.ParseStandard(schedule.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %q: %w", schedule.Cron, err)
+	}
+	if cronSpec, ok := spec.(*cron.SpecSchedule); ok {
+		cronSpec.Location = location
+	}
+	return spec, nil
+}
@@ -5,6 +5,9 @@
 package steps
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/carloskvasir/goflow/internal/models"
 )
 
@@ -21,8 +24,11 @@ func NewEchoStep(config models.StepConfig) *EchoStep {
 }
 
 // Execute returns the message from the config
-func (s *EchoStep) Execute(context map[string]interface{}) (*models.StepResult, error) {
-	message := s.config["message"].(string)
+func (s *EchoStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
+	message, ok := s.config["message"].(string)
+	if !ok {
+		return nil, fmt.Errorf("config key message must be a string")
+	}
 
 	return &models.StepResult{
 		Status: models.StatusCompleted,
@@ -0,0 +1,279 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package steps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/connectors"
+	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/tidwall/gjson"
+)
+
+// DefaultMaxPages bounds the pages fetched by a paginated request whose
+// pagination sets no max_pages
+const DefaultMaxPages = 100
+
+// Pagination styles
+const (
+	PaginationPage   = "page"   // Page number in a query parameter
+	PaginationOffset = "offset" // Offset of the first item in a query parameter
+	PaginationCursor = "cursor" // Cursor read from the response body
+	PaginationLink   = "link"   // URL of the next page in the Link header (RFC 5988)
+)
+
+// pagination is the "pagination" block of a rest step. The items of every page
+// are read from the Items JSON path of the response body and concatenated into
+// a single array, which becomes the data of the step.
+type pagination struct {
+	Type     string `json:"type"`
+	Items    string `json:"items"`     // JSON path of the items in the body; empty if the body is the array
+	MaxPages int    `json:"max_pages"` // Maximum number of pages fetched (default DefaultMaxPages)
+	MaxItems int    `json:"max_items"` // Maximum number of items collected (0 means unlimited)
+
+	// page
+	PageParam string `json:"page_param"` // Query parameter of the page number (default "page")
+	Start     *int   `json:"start"`      // Number of the first page (default 1)
+	SizeParam string `json:"size_param"` // Query parameter of the page size, sent if Size is set
+
+	// offset
+	OffsetParam string `json:"offset_param"` // Query parameter of the offset (default "offset")
+	LimitParam  string `json:"limit_param"`  // Query parameter of the page size (default "limit")
+
+	// page and offset: a page with fewer items than Size is the last one
+	Size int `json:"size"`
+
+	// cursor
+	CursorPath  string `json:"cursor_path"`  // JSON path of the next cursor in the body
+	CursorParam string `json:"cursor_param"` // Query parameter of the cursor (default "cursor")
+}
+
+// ValidatePagination checks the "pagination" block of a rest step config
+func ValidatePagination(config models.StepConfig) error {
+	_, err := parsePagination(config)
+	return err
+}
+
+// parsePagination decodes the "pagination" block of a rest step config and
+// fills in its defaults. It returns nil if the step is not paginated.
+func parsePagination(config models.StepConfig) (*pagination, error) {
+	raw, exists := config["pagination"]
+	if !exists || raw == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pagination: %w", err)
+	}
+	var p pagination
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid pagination: %w", err)
+	}
+
+	if p.MaxPages < 0 || p.MaxItems < 0 || p.Size < 0 {
+		return nil, fmt.Errorf("pagination limits cannot be negative")
+	}
+	if p.MaxPages == 0 {
+		p.MaxPages = DefaultMaxPages
+	}
+
+	switch p.Type {
+	case PaginationPage:
+		if p.PageParam == "" {
+			p.PageParam = "page"
+		}
+		if p.Start == nil {
+			start := 1
+			p.Start = &start
+		}
+	case PaginationOffset:
+		if p.OffsetParam == "" {
+			p.OffsetParam = "offset"
+		}
+		if p.LimitParam == "" {
+			p.LimitParam = "limit"
+		}
+	case PaginationCursor:
+		if p.CursorPath == "" {
+			return nil, fmt.Errorf("cursor pagination requires cursor_path")
+		}
+		if p.CursorParam == "" {
+			p.CursorParam = "cursor"
+		}
+	case PaginationLink:
+	default:
+		return nil, fmt.Errorf("unknown pagination type: %q", p.Type)
+	}
+	return &p, nil
+}
+
+// paginate fetches the pages of a paginated request and concatenates their items
+func (s *RestStep) paginate(ctx context.Context, connector connectors.Connector, req connectors.Request, p *pagination) (*models.StepResult, error) {
+	baseURL := req.URL
+	items := make([]interface{}, 0)
+	pages, page, offset, truncated := 0, 0, 0, false
+	if p.Start != nil {
+		page = *p.Start
+	}
+
+	// Parâmetros da primeira página
+	switch p.Type {
+	case PaginationPage:
+		req.URL = addQueryParams(baseURL, p.pageParams(page))
+	case PaginationOffset:
+		req.URL = addQueryParams(baseURL, p.offsetParams(offset))
+	}
+
+	var resp *connectors.Response
+	var waited time.Duration
+	for {
+		var err error
+		resp, err = s.send(ctx, connector, req)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pages+1, err)
+		}
+		pages++
+		waited += resp.RateLimitWait
+
+		pageItems, err := p.pageItems(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", pages, err)
+		}
+		items = append(items, pageItems...)
+		if p.MaxItems > 0 && len(items) >= p.MaxItems {
+			truncated = len(items) > p.MaxItems || p.hasNext(resp, pageItems)
+			items = items[:p.MaxItems]
+			break
+		}
+
+		if !p.hasNext(resp, pageItems) {
+			break
+		}
+		if pages >= p.MaxPages {
+			truncated = true
+			break
+		}
+
+		// Parâmetros da próxima página
+		switch p.Type {
+		case PaginationPage:
+			page++
+			req.URL = addQueryParams(baseURL, p.pageParams(page))
+		case PaginationOffset:
+			offset += len(pageItems)
+			req.URL = addQueryParams(baseURL, p.offsetParams(offset))
+		case PaginationCursor:
+			cursor := gjson.GetBytes(resp.Body, p.CursorPath).String()
+			req.URL = addQueryParams(baseURL, map[string]interface{}{p.CursorParam: cursor})
+		case PaginationLink:
+			req.URL = resolveURL(req.URL, nextLink(resp.Headers["Link"]))
+		}
+	}
+
+	metadata := responseMetadata(resp)
+	metadata["pages"] = pages
+	metadata["items"] = len(items)
+	metadata["truncated"] = truncated
+	if waited > 0 {
+		metadata["rate_limit_wait"] = waited
+	}
+
+	return &models.StepResult{
+		Status:   models.StatusCompleted,
+		Data:     items,
+		Metadata: metadata,
+	}, nil
+}
+
+// pageItems returns the items of a page
+func (p *pagination) pageItems(body []byte) ([]interface{}, error) {
+	value := gjson.ParseBytes(body)
+	if p.Items != "" {
+		value = gjson.GetBytes(body, p.Items)
+	}
+	if !value.IsArray() {
+		if p.Items == "" {
+			return nil, fmt.Errorf("response body is not an array")
+		}
+		return nil, fmt.Errorf("pagination items %s is not an array", p.Items)
+	}
+
+	items, _ := value.Value().([]interface{})
+	return items, nil
+}
+
+// hasNext reports whether there is a page after the one just fetched
+func (p *pagination) hasNext(resp *connectors.Response, pageItems []interface{}) bool {
+	switch p.Type {
+	case PaginationPage, PaginationOffset:
+		return len(pageItems) > 0 && (p.Size == 0 || len(pageItems) >= p.Size)
+	case PaginationCursor:
+		cursor := gjson.GetBytes(resp.Body, p.CursorPath)
+		return cursor.Exists() && cursor.Type != gjson.Null && cursor.String() != ""
+	case PaginationLink:
+		return nextLink(resp.Headers["Link"]) != ""
+	}
+	return false
+}
+
+func (p *pagination) pageParams(page int) map[string]interface{} {
+	params := map[string]interface{}{p.PageParam: page}
+	if p.SizeParam != "" && p.Size > 0 {
+		params[p.SizeParam] = p.Size
+	}
+	return params
+}
+
+func (p *pagination) offsetParams(offset int) map[string]interface{} {
+	params := map[string]interface{}{p.OffsetParam: offset}
+	if p.Size > 0 {
+		params[p.LimitParam] = p.Size
+	}
+	return params
+}
+
+// nextLink returns the URL of the rel="next" link of a Link header, or an
+// empty string if there is none, e.g. for
+// `<https://api.example.com/items?page=2>; rel="next", <...>; rel="last"`
+func nextLink(header string) string {
+	for _, link := range strings.Split(header, ",") {
+		parts := strings.Split(link, ";")
+		target := strings.TrimSpace(parts[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range parts[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+				continue
+			}
+			for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+				if strings.EqualFold(rel, "next") {
+					return target[1 : len(target)-1]
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// resolveURL resolves a link against the URL of the request it came from
+func resolveURL(current, link string) string {
+	base, err := url.Parse(current)
+	if err != nil {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,132 @@
+package steps
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// itemsServer serve 7 itens em páginas, nos estilos page, offset, cursor e link
+func itemsServer() *httptest.Server {
+	const total = 7
+	page := func(from, size int) []string {
+		var items []string
+		for i := from; i < from+size && i < total; i++ {
+			items = append(items, strconv.Itoa(i+1))
+		}
+		return items
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		number, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		fmt.Fprintf(w, `{"data": [%s]}`, strings.Join(page((number-1)*size, size), ","))
+	})
+	mux.HandleFunc("/offset", func(w http.ResponseWriter, r *http.Request) {
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		fmt.Fprintf(w, `[%s]`, strings.Join(page(offset, limit), ","))
+	})
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		next := `null`
+		if from+3 < total {
+			next = strconv.Quote(strconv.Itoa(from + 3))
+		}
+		fmt.Fprintf(w, `{"results": [%s], "meta": {"next": %s}}`, strings.Join(page(from, 3), ","), next)
+	})
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		from, _ := strconv.Atoi(r.URL.Query().Get("from"))
+		if from+3 < total {
+			w.Header().Set("Link", fmt.Sprintf(`</link?from=%d>; rel="next", </link?from=6>; rel="last"`, from+3))
+		}
+		fmt.Fprintf(w, `[%s]`, strings.Join(page(from, 3), ","))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestRestStepPagination(t *testing.T) {
+	server := itemsServer()
+	defer server.Close()
+
+	all := []interface{}{1.0, 2.0, 3.0, 4.0, 5.0, 6.0, 7.0}
+	tests := []struct {
+		name       string
+		path       string
+		pagination map[string]interface{}
+		items      []interface{}
+		pages      int
+		truncated  bool
+	}{
+		{"page", "/page", map[string]interface{}{"type": "page", "items": "data", "size_param": "per_page", "size": 3}, all, 3, false},
+		{"offset", "/offset", map[string]interface{}{"type": "offset", "size": 2}, all, 4, false},
+		{"cursor", "/cursor", map[string]interface{}{"type": "cursor", "items": "results", "cursor_path": "meta.next"}, all, 3, false},
+		{"link", "/link", map[string]interface{}{"type": "link"}, all, 3, false},
+		{"max pages", "/link", map[string]interface{}{"type": "link", "max_pages": 2}, all[:6], 2, true},
+		{"max items", "/cursor", map[string]interface{}{"type": "cursor", "items": "results", "cursor_path": "meta.next", "max_items": 4}, all[:4], 2, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := NewRestStep(models.StepConfig{"method": "GET", "url": server.URL + tt.path, "pagination": tt.pagination})
+			result, err := step.Execute(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("Erro ao executar step: %v", err)
+			}
+
+			if !reflect.DeepEqual(result.Data, tt.items) {
+				t.Errorf("Esperava itens %v, mas obteve %v", tt.items, result.Data)
+			}
+			if pages := result.Metadata["pages"]; pages != tt.pages {
+				t.Errorf("Esperava %d páginas, mas obteve %v", tt.pages, pages)
+			}
+			if truncated := result.Metadata["truncated"]; truncated != tt.truncated {
+				t.Errorf("Esperava truncated=%v, mas obteve %v", tt.truncated, truncated)
+			}
+		})
+	}
+}
+
+func TestRestStepPaginationErrors(t *testing.T) {
+	server := itemsServer()
+	defer server.Close()
+
+	invalid := []map[string]interface{}{
+		{"type": "scroll"},
+		{"type": "cursor"},
+		{"type": "page", "max_pages": -1},
+	}
+	for _, pagination := range invalid {
+		if err := ValidatePagination(models.StepConfig{"pagination": pagination}); err == nil {
+			t.Errorf("Esperava erro para a paginação %v", pagination)
+		}
+	}
+
+	// Itens que não são uma lista falham o step
+	step := NewRestStep(models.StepConfig{"method": "GET", "url": server.URL + "/cursor", "pagination": map[string]interface{}{"type": "link", "items": "meta"}})
+	if _, err := step.Execute(context.Background(), nil); err == nil || !strings.Contains(err.Error(), "not an array") {
+		t.Errorf("Erro inesperado: %v", err)
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := map[string]string{
+		`<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=5>; rel="last"`: "https://api.example.com/items?page=2",
+		`<https://api.example.com/items?page=1>; rel="prev first"`:                                               "",
+		`</items?page=3>; title="x"; rel="next last"`:                                                            "/items?page=3",
+		``: "",
+	}
+	for header, expected := range tests {
+		if got := nextLink(header); got != expected {
+			t.Errorf("nextLink(%q) = %q, esperava %q", header, got, expected)
+		}
+	}
+}
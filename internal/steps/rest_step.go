@@ -5,21 +5,26 @@
 package steps
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"strings"
 
+	"github.com/carloskvasir/goflow/internal/connectors"
 	"github.com/carloskvasir/goflow/internal/models"
+	"github.com/carloskvasir/goflow/internal/retry"
 )
 
-// RestStep executes HTTP requests
+// DefaultRestTimeout bounds the requests of a rest step whose connection sets
+// no timeout and whose context has no deadline, i.e. a step without timeout
+const DefaultRestTimeout = connectors.DefaultTimeout
+
+// RestStep executes HTTP requests through a connector
 type RestStep struct {
-	config models.StepConfig
+	config    models.StepConfig
+	connector connectors.Connector
 }
 
 // NewRestStep creates a new REST step
@@ -29,11 +34,28 @@ func NewRestStep(config models.StepConfig) *RestStep {
 	}
 }
 
+// ConnectionName returns the connection referenced by the "connection" key
+func (s *RestStep) ConnectionName() string {
+	name, _ := s.config["connection"].(string)
+	return name
+}
+
+// UseConnector sets the connector the request is executed through
+func (s *RestStep) UseConnector(connector connectors.Connector) {
+	s.connector = connector
+}
+
 // Execute performs the HTTP request
-func (s *RestStep) Execute(context map[string]interface{}) (*models.StepResult, error) {
+func (s *RestStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
 	// Get configuration
-	method := s.config["method"].(string)
-	urlStr := s.config["url"].(string)
+	method, ok := s.config["method"].(string)
+	if !ok {
+		return nil, fmt.Errorf("config key method must be a string")
+	}
+	urlStr, ok := s.config["url"].(string)
+	if !ok {
+		return nil, fmt.Errorf("config key url must be a string")
+	}
 
 	// Process environment variables
 	urlStr = processEnvVars(urlStr)
@@ -43,63 +65,92 @@ func (s *RestStep) Execute(context map[string]interface{}) (*models.StepResult,
 		urlStr = addQueryParams(urlStr, params)
 	}
 
-	// Prepare request body if present
-	var body io.Reader
-	if data, ok := s.config["body"]; ok {
-		jsonData, err := json.Marshal(data)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		body = bytes.NewBuffer(jsonData)
-	}
-
-	// Create request
-	req, err := http.NewRequest(method, urlStr, body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	req := connectors.Request{
+		Method:  method,
+		URL:     urlStr,
+		Headers: make(map[string]string),
+		Body:    s.config["body"],
 	}
 
 	// Add headers if present
 	if headers, ok := s.config["headers"].(map[string]interface{}); ok {
 		for key, value := range headers {
-			req.Header.Set(key, fmt.Sprintf("%v", value))
+			req.Headers[key] = fmt.Sprintf("%v", value)
+		}
+	}
+
+	// Sem conexão injetada, usa um conector próprio apenas para esta requisição
+	connector := s.connector
+	if connector == nil {
+		connector = connectors.NewRestConnector(connectors.Config{})
+		if err := connector.Connect(ctx); err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
 		}
+		defer connector.Close()
 	}
 
-	// Execute request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	pagination, err := parsePagination(s.config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, err
+	}
+	if pagination != nil {
+		return s.paginate(ctx, connector, req, pagination)
 	}
-	defer resp.Body.Close()
 
-	// Read response body
-	respBody, err := io.ReadAll(resp.Body)
+	resp, err := s.send(ctx, connector, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, err
 	}
 
-	// Parse JSON response
-	var responseData interface{}
-	if err := json.Unmarshal(respBody, &responseData); err != nil {
-		// If not JSON, use raw response
-		responseData = string(respBody)
+	return &models.StepResult{
+		Status:   models.StatusCompleted,
+		Data:     parseResponseBody(resp.Body),
+		Metadata: responseMetadata(resp),
+	}, nil
+}
+
+// send executes a request and checks its status code
+func (s *RestStep) send(ctx context.Context, connector connectors.Connector, req connectors.Request) (*connectors.Response, error) {
+	resp, err := connector.Execute(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 
 	// Check status code
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, respBody)
+		return nil, retry.NewStatusError(resp.StatusCode, resp.Body, resp.Headers["Retry-After"])
 	}
+	return resp, nil
+}
 
-	return &models.StepResult{
-		Status: models.StatusCompleted,
-		Data:   responseData,
-	}, nil
+// responseMetadata returns the metadata of a step result for a response: its
+// status code, every attempt made if the connector retried the request and
+// the time spent waiting for the rate limit of the connection.
+func responseMetadata(resp *connectors.Response) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"status_code": resp.StatusCode,
+	}
+	if len(resp.Attempts) > 1 {
+		metadata["requests"] = resp.Attempts
+	}
+	if resp.RateLimitWait > 0 {
+		metadata["rate_limit_wait"] = resp.RateLimitWait
+	}
+	return metadata
 }
 
 // Helper functions
 
+// parseResponseBody decodes a JSON response body, or returns it as a string if
+// it is not JSON
+func parseResponseBody(body []byte) interface{} {
+	var responseData interface{}
+	if err := json.Unmarshal(body, &responseData); err != nil {
+		return string(body)
+	}
+	return responseData
+}
+
 func processEnvVars(input string) string {
 	result := input
 	// Find all ${VAR} patterns
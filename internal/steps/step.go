@@ -0,0 +1,82 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package steps
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/carloskvasir/goflow/internal/connectors"
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// StepExecutor is the interface implemented by every step type.
+type StepExecutor interface {
+	// Execute runs the step with the data accumulated in the execution context.
+	// Implementations must stop when ctx is done.
+	Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error)
+}
+
+// ConnectionUser is implemented by steps that execute through a connector. The
+// engine hands them the connector of the named connection they reference, or
+// its default connector when the name is empty.
+type ConnectionUser interface {
+	// ConnectionName returns the name of the connection used by the step
+	ConnectionName() string
+
+	// UseConnector sets the connector the step executes through
+	UseConnector(connector connectors.Connector)
+}
+
+// StepFactory creates a StepExecutor from the configuration of a step
+type StepFactory func(config models.StepConfig) (StepExecutor, error)
+
+// RequireConfig checks that the given keys are present in the step configuration
+func RequireConfig(config models.StepConfig, keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		if _, ok := config[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required config keys: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// RequireString checks that the given keys of the step configuration, when
+// present, are strings
+func RequireString(config models.StepConfig, keys ...string) error {
+	for _, key := range keys {
+		if value, ok := config[key]; ok {
+			if _, ok := value.(string); !ok {
+				return fmt.Errorf("config key %s must be a string", key)
+			}
+		}
+	}
+	return nil
+}
+
+// RequireStringMap checks that the given key of the step configuration, when
+// present, is an object whose values are strings
+func RequireStringMap(config models.StepConfig, key string) error {
+	value, ok := config[key]
+	if !ok {
+		return nil
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("config key %s must be an object", key)
+	}
+	for name, item := range object {
+		if _, ok := item.(string); !ok {
+			return fmt.Errorf("config key %s.%s must be a string", key, name)
+		}
+	}
+	return nil
+}
@@ -6,7 +6,9 @@ package steps
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"text/template"
 
 	"github.com/carloskvasir/goflow/internal/models"
@@ -26,23 +28,33 @@ func NewTransformStep(config models.StepConfig) *TransformStep {
 }
 
 // Execute processes the transformation
-func (s *TransformStep) Execute(context map[string]interface{}) (*models.StepResult, error) {
+func (s *TransformStep) Execute(ctx context.Context, execCtx map[string]interface{}) (*models.StepResult, error) {
 	// Get template and mapping from config
-	templateStr := s.config["template"].(string)
-	mapping := s.config["mapping"].(map[string]interface{})
+	templateStr, ok := s.config["template"].(string)
+	if !ok {
+		return nil, fmt.Errorf("config key template must be a string")
+	}
+	mapping, ok := s.config["mapping"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("config key mapping must be an object")
+	}
+
+	// Convert context to JSON to use gjson
+	contextJSON, err := json.Marshal(execCtx)
+	if err != nil {
+		return nil, err
+	}
 
 	// Create data map for template
 	data := make(map[string]interface{})
-	
+
 	// Process each mapping
 	for key, path := range mapping {
-		jsonPath := path.(string)
-		// Convert context to JSON to use gjson
-		contextJSON, err := json.Marshal(context)
-		if err != nil {
-			return nil, err
+		jsonPath, ok := path.(string)
+		if !ok {
+			return nil, fmt.Errorf("mapping %s must be a JSON path string", key)
 		}
-		value := gjson.Get(string(contextJSON), jsonPath)
+		value := gjson.GetBytes(contextJSON, jsonPath)
 		data[key] = value.Value()
 	}
 
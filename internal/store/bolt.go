@@ -0,0 +1,161 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	workflowsBucket = []byte("workflows")
+	runsBucket      = []byte("runs")
+)
+
+// BoltStore implements the Store interface on top of an embedded BoltDB file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// NewBoltStore opens (or creates) the BoltDB file at path
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening store %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{workflowsBucket, runsBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing store %s: %w", path, err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// SaveWorkflow implements the SaveWorkflow method of the Store interface
+func (b *BoltStore) SaveWorkflow(workflow *models.Workflow) error {
+	return b.put(workflowsBucket, workflow.ID, workflow)
+}
+
+// GetWorkflow implements the GetWorkflow method of the Store interface
+func (b *BoltStore) GetWorkflow(id string) (*models.Workflow, error) {
+	var workflow models.Workflow
+	if err := b.get(workflowsBucket, id, &workflow); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// ListWorkflows implements the ListWorkflows method of the Store interface
+func (b *BoltStore) ListWorkflows() ([]*models.Workflow, error) {
+	var workflows []*models.Workflow
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(workflowsBucket).ForEach(func(k, v []byte) error {
+			var workflow models.Workflow
+			if err := json.Unmarshal(v, &workflow); err != nil {
+				return fmt.Errorf("error decoding workflow %s: %w", k, err)
+			}
+			workflows = append(workflows, &workflow)
+			return nil
+		})
+	})
+	return workflows, err
+}
+
+// DeleteWorkflow implements the DeleteWorkflow method of the Store interface
+func (b *BoltStore) DeleteWorkflow(id string) error {
+	return b.delete(workflowsBucket, id)
+}
+
+// SaveRun implements the SaveRun method of the Store interface
+func (b *BoltStore) SaveRun(run *models.Run) error {
+	return b.put(runsBucket, run.ID, run)
+}
+
+// GetRun implements the GetRun method of the Store interface
+func (b *BoltStore) GetRun(id string) (*models.Run, error) {
+	var run models.Run
+	if err := b.get(runsBucket, id, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// ListRuns implements the ListRuns method of the Store interface
+func (b *BoltStore) ListRuns(workflowID string) ([]*models.Run, error) {
+	var runs []*models.Run
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(runsBucket).ForEach(func(k, v []byte) error {
+			var run models.Run
+			if err := json.Unmarshal(v, &run); err != nil {
+				return fmt.Errorf("error decoding run %s: %w", k, err)
+			}
+			if run.WorkflowID == workflowID {
+				runs = append(runs, &run)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortRuns(runs)
+	return runs, nil
+}
+
+// DeleteRun implements the DeleteRun method of the Store interface
+func (b *BoltStore) DeleteRun(id string) error {
+	return b.delete(runsBucket, id)
+}
+
+// Close implements the Close method of the Store interface
+func (b *BoltStore) Close() error {
+	return b.db.Close()
+}
+
+// Helper methods
+
+// put encodes a value as JSON and stores it under key
+func (b *BoltStore) put(bucket []byte, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", key, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), data)
+	})
+}
+
+// get decodes the value stored under key
+func (b *BoltStore) get(bucket []byte, key string, value interface{}) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(bucket).Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, value)
+	})
+}
+
+// delete removes the value stored under key
+func (b *BoltStore) delete(bucket []byte, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
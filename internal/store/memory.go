@@ -0,0 +1,139 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store
+
+import (
+	"sync"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// MemoryStore implements the Store interface in memory. Its content is lost
+// when the process exits.
+type MemoryStore struct {
+	workflows map[string]*models.Workflow
+	runs      map[string]*models.Run
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates a new instance of MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		workflows: make(map[string]*models.Workflow),
+		runs:      make(map[string]*models.Run),
+	}
+}
+
+// SaveWorkflow implements the SaveWorkflow method of the Store interface
+func (m *MemoryStore) SaveWorkflow(workflow *models.Workflow) error {
+	copied, err := clone(workflow)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.workflows[workflow.ID] = copied
+	return nil
+}
+
+// GetWorkflow implements the GetWorkflow method of the Store interface
+func (m *MemoryStore) GetWorkflow(id string) (*models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	workflow, exists := m.workflows[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(workflow)
+}
+
+// ListWorkflows implements the ListWorkflows method of the Store interface
+func (m *MemoryStore) ListWorkflows() ([]*models.Workflow, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	workflows := make([]*models.Workflow, 0, len(m.workflows))
+	for _, workflow := range m.workflows {
+		copied, err := clone(workflow)
+		if err != nil {
+			return nil, err
+		}
+		workflows = append(workflows, copied)
+	}
+	return workflows, nil
+}
+
+// DeleteWorkflow implements the DeleteWorkflow method of the Store interface
+func (m *MemoryStore) DeleteWorkflow(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.workflows, id)
+	return nil
+}
+
+// SaveRun implements the SaveRun method of the Store interface
+func (m *MemoryStore) SaveRun(run *models.Run) error {
+	copied, err := clone(run)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.runs[run.ID] = copied
+	return nil
+}
+
+// GetRun implements the GetRun method of the Store interface
+func (m *MemoryStore) GetRun(id string) (*models.Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	run, exists := m.runs[id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return clone(run)
+}
+
+// ListRuns implements the ListRuns method of the Store interface
+func (m *MemoryStore) ListRuns(workflowID string) ([]*models.Run, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var runs []*models.Run
+	for _, run := range m.runs {
+		if run.WorkflowID != workflowID {
+			continue
+		}
+		copied, err := clone(run)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, copied)
+	}
+
+	sortRuns(runs)
+	return runs, nil
+}
+
+// DeleteRun implements the DeleteRun method of the Store interface
+func (m *MemoryStore) DeleteRun(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.runs, id)
+	return nil
+}
+
+// Close implements the Close method of the Store interface
+func (m *MemoryStore) Close() error {
+	return nil
+}
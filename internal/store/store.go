@@ -0,0 +1,68 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// ErrNotFound is returned when a workflow or run does not exist in the store
+var ErrNotFound = errors.New("not found")
+
+// Store persists workflow definitions and runs, including their step results
+type Store interface {
+	// SaveWorkflow creates or replaces a workflow definition
+	SaveWorkflow(workflow *models.Workflow) error
+
+	// GetWorkflow returns a workflow definition by its ID
+	GetWorkflow(id string) (*models.Workflow, error)
+
+	// ListWorkflows returns every stored workflow definition
+	ListWorkflows() ([]*models.Workflow, error)
+
+	// DeleteWorkflow removes a workflow definition
+	DeleteWorkflow(id string) error
+
+	// SaveRun creates or replaces a run
+	SaveRun(run *models.Run) error
+
+	// GetRun returns a run by its ID
+	GetRun(id string) (*models.Run, error)
+
+	// ListRuns returns the runs of a workflow, most recent first
+	ListRuns(workflowID string) ([]*models.Run, error)
+
+	// DeleteRun removes a run
+	DeleteRun(id string) error
+
+	// Close releases the resources held by the store
+	Close() error
+}
+
+// clone returns a deep copy of a value by encoding it to JSON, so that stored
+// values never share memory with the engine regardless of the backend
+func clone[T any](value *T) (*T, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied T
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// sortRuns orders runs from the most recent to the oldest
+func sortRuns(runs []*models.Run) {
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].CreatedAt.After(runs[j].CreatedAt)
+	})
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// testStore exercita o contrato da interface Store em qualquer backend
+func testStore(t *testing.T, s Store) {
+	workflow := &models.Workflow{
+		ID:   "store-workflow",
+		Name: "Store Workflow",
+		Steps: []models.Step{
+			{ID: "step1", Type: "echo", Config: map[string]interface{}{"message": "hi"}},
+		},
+	}
+	if err := s.SaveWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao salvar workflow: %v", err)
+	}
+
+	stored, err := s.GetWorkflow(workflow.ID)
+	if err != nil {
+		t.Fatalf("Erro ao obter workflow: %v", err)
+	}
+	if stored.Steps[0].Config["message"] != "hi" {
+		t.Errorf("Workflow inesperado: %+v", stored)
+	}
+
+	now := time.Now()
+	for i, id := range []string{"run-1", "run-2"} {
+		run := &models.Run{
+			ID:         id,
+			WorkflowID: workflow.ID,
+			Status:     models.StatusCompleted,
+			CreatedAt:  now.Add(time.Duration(i) * time.Second),
+			Result: &models.WorkflowResult{
+				WorkflowID: workflow.ID,
+				RunID:      id,
+				StepResults: map[string]models.StepResult{
+					"step1": {StepID: "step1", Status: models.StatusCompleted, Data: "hi"},
+				},
+			},
+		}
+		if err := s.SaveRun(run); err != nil {
+			t.Fatalf("Erro ao salvar execução: %v", err)
+		}
+	}
+
+	runs, err := s.ListRuns(workflow.ID)
+	if err != nil {
+		t.Fatalf("Erro ao listar execuções: %v", err)
+	}
+	if len(runs) != 2 || runs[0].ID != "run-2" {
+		t.Errorf("Esperava run-2 e run-1, mas obteve %v", runs)
+	}
+	if runs[0].Result.StepResults["step1"].Data != "hi" {
+		t.Errorf("Resultado de step inesperado: %+v", runs[0].Result.StepResults)
+	}
+
+	if err := s.DeleteRun("run-1"); err != nil {
+		t.Fatalf("Erro ao remover execução: %v", err)
+	}
+	if _, err := s.GetRun("run-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Esperava ErrNotFound, mas obteve %v", err)
+	}
+
+	if err := s.DeleteWorkflow(workflow.ID); err != nil {
+		t.Fatalf("Erro ao remover workflow: %v", err)
+	}
+	workflows, err := s.ListWorkflows()
+	if err != nil || len(workflows) != 0 {
+		t.Errorf("Esperava nenhum workflow, mas obteve %v (%v)", workflows, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestBoltStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goflow.db")
+
+	s, err := NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("Erro ao abrir store: %v", err)
+	}
+	testStore(t, s)
+
+	// Os dados sobrevivem ao fechamento e reabertura do arquivo
+	if err := s.SaveWorkflow(&models.Workflow{ID: "persisted"}); err != nil {
+		t.Fatalf("Erro ao salvar workflow: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Erro ao fechar store: %v", err)
+	}
+
+	s, err = NewBoltStore(path)
+	if err != nil {
+		t.Fatalf("Erro ao reabrir store: %v", err)
+	}
+	defer s.Close()
+
+	if _, err := s.GetWorkflow("persisted"); err != nil {
+		t.Errorf("Erro ao obter workflow persistido: %v", err)
+	}
+}
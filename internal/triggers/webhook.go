@@ -0,0 +1,326 @@
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this
+// file, You can obtain one at http://mozilla.org/MPL/2.0/.
+
+package triggers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/carloskvasir/goflow/internal/core"
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+const (
+	// DefaultSignatureHeader carries the HMAC-SHA256 signature of the body
+	DefaultSignatureHeader = "X-Signature-256"
+
+	// DefaultDeliveryIDHeader carries the ID used to deduplicate deliveries
+	DefaultDeliveryIDHeader = "X-Delivery-ID"
+
+	// DefaultDeliveryTTL is how long delivery IDs are remembered
+	DefaultDeliveryTTL = 24 * time.Hour
+
+	// MaxBodySize is the largest request body accepted by a webhook
+	MaxBodySize = 1 << 20
+)
+
+// Engine is the part of the workflow engine used by the webhooks
+type Engine interface {
+	ListWorkflows() []*models.Workflow
+	StartWorkflow(workflowID string, inputs map[string]interface{}) (*models.Run, error)
+	ExecuteWorkflowWithInputs(ctx context.Context, workflowID string, inputs map[string]interface{}) (*models.WorkflowResult, error)
+	GetRun(runID string) (*models.Run, bool)
+}
+
+// delivery is a webhook delivery already received
+type delivery struct {
+	runID      string // Empty while the run is being started
+	receivedAt time.Time
+}
+
+// Webhooks starts workflows from inbound HTTP requests
+type Webhooks struct {
+	engine      Engine
+	deliveryTTL time.Duration
+	deliveries  map[string]delivery
+	mu          sync.Mutex
+}
+
+// NewWebhooks creates the webhook triggers of the engine's workflows
+func NewWebhooks(engine Engine) *Webhooks {
+	return &Webhooks{
+		engine:      engine,
+		deliveryTTL: DefaultDeliveryTTL,
+		deliveries:  make(map[string]delivery),
+	}
+}
+
+// Handle starts the workflow whose webhook path or ID is name. The run starts
+// in the background and the response is 202 with the run, unless the webhook
+// has a response step: then the request waits for the run and the response is
+// the data of that step.
+func (h *Webhooks) Handle(w http.ResponseWriter, r *http.Request, name string) {
+	workflow, webhook := h.resolve(name)
+	if workflow == nil {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"error": "webhook not found"})
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, status, map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	signatureHeader := webhook.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = DefaultSignatureHeader
+	}
+	if webhook.Secret != "" {
+		if !VerifySignature(webhook.Secret, body, r.Header.Get(signatureHeader)) {
+			writeJSON(w, http.StatusUnauthorized, map[string]interface{}{"error": "invalid signature"})
+			return
+		}
+	}
+
+	header := webhook.DeliveryIDHeader
+	if header == "" {
+		header = DefaultDeliveryIDHeader
+	}
+	deliveryID := r.Header.Get(header)
+	if deliveryID != "" {
+		if runID, duplicate := h.reserveDelivery(workflow.ID, deliveryID); duplicate {
+			h.writeDuplicate(w, runID)
+			return
+		}
+	}
+
+	inputs := RequestInputs(r, body, signatureHeader)
+	inputs["delivery_id"] = deliveryID
+
+	if webhook.ResponseStep == "" {
+		run, err := h.engine.StartWorkflow(workflow.ID, inputs)
+		if err != nil {
+			h.releaseDelivery(workflow.ID, deliveryID)
+			writeError(w, err)
+			return
+		}
+
+		h.recordDelivery(workflow.ID, deliveryID, run.ID)
+		writeJSON(w, http.StatusAccepted, run)
+		return
+	}
+
+	result, err := h.engine.ExecuteWorkflowWithInputs(r.Context(), workflow.ID, inputs)
+	if result == nil {
+		h.releaseDelivery(workflow.ID, deliveryID)
+		writeError(w, err)
+		return
+	}
+
+	h.recordDelivery(workflow.ID, deliveryID, result.RunID)
+	w.Header().Set("X-Run-ID", result.RunID)
+
+	stepResult, exists := result.StepResults[webhook.ResponseStep]
+	if err != nil || !exists || stepResult.Status != models.StatusCompleted {
+		message := stepResult.Error
+		if err != nil {
+			message = err.Error()
+		}
+		writeJSON(w, http.StatusBadGateway, map[string]interface{}{
+			"error":  message,
+			"run_id": result.RunID,
+			"status": result.Status,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stepResult.Data)
+}
+
+// resolve returns the workflow whose webhook path, or ID, is name
+func (h *Webhooks) resolve(name string) (*models.Workflow, *models.Webhook) {
+	var byID *models.Workflow
+	for _, workflow := range h.engine.ListWorkflows() {
+		if workflow.Webhook == nil {
+			continue
+		}
+		if workflow.Webhook.Path != "" && workflow.Webhook.Path == name {
+			return workflow, workflow.Webhook
+		}
+		if workflow.ID == name {
+			byID = workflow
+		}
+	}
+
+	if byID == nil {
+		return nil, nil
+	}
+	return byID, byID.Webhook
+}
+
+// reserveDelivery records a delivery before its run starts. It reports whether
+// the delivery was already received, and the run it started, if any.
+func (h *Webhooks) reserveDelivery(workflowID, deliveryID string) (string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now()
+	for key, d := range h.deliveries {
+		if now.Sub(d.receivedAt) > h.deliveryTTL {
+			delete(h.deliveries, key)
+		}
+	}
+
+	key := workflowID + "/" + deliveryID
+	if d, exists := h.deliveries[key]; exists {
+		return d.runID, true
+	}
+
+	h.deliveries[key] = delivery{receivedAt: now}
+	return "", false
+}
+
+// recordDelivery records the run started by a delivery
+func (h *Webhooks) recordDelivery(workflowID, deliveryID, runID string) {
+	if deliveryID == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := workflowID + "/" + deliveryID
+	if d, exists := h.deliveries[key]; exists {
+		d.runID = runID
+		h.deliveries[key] = d
+	}
+}
+
+// releaseDelivery forgets a delivery whose run could not start, so that it can
+// be retried
+func (h *Webhooks) releaseDelivery(workflowID, deliveryID string) {
+	if deliveryID == "" {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.deliveries, workflowID+"/"+deliveryID)
+}
+
+// writeDuplicate responds to a delivery that was already received
+func (h *Webhooks) writeDuplicate(w http.ResponseWriter, runID string) {
+	if runID == "" {
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"error": "delivery is already being processed"})
+		return
+	}
+
+	response := map[string]interface{}{"duplicate": true, "run_id": runID}
+	if run, exists := h.engine.GetRun(runID); exists {
+		response["run"] = run
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// VerifySignature checks the HMAC-SHA256 signature of a body. The signature is
+// hex encoded, optionally prefixed with "sha256=".
+func VerifySignature(secret string, body []byte, signature string) bool {
+	signature = strings.TrimPrefix(signature, "sha256=")
+	received, err := hex.DecodeString(signature)
+	if err != nil || len(received) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(received, mac.Sum(nil))
+}
+
+// SensitiveHeaders lists the request headers left out of the inputs of a run,
+// since runs are persisted and returned by the API
+var SensitiveHeaders = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"X-Api-Key",
+	DefaultSignatureHeader,
+}
+
+// RequestInputs converts a webhook request into the inputs of a run: "body"
+// holds the decoded JSON body, or the raw body if it is not JSON, "headers"
+// the headers with lower case names, and "query" the query parameters. Repeated
+// headers are joined with ", " and only the first value of a repeated query
+// parameter is kept. SensitiveHeaders and the excluded headers, such as the
+// signature header of the webhook, are left out.
+func RequestInputs(r *http.Request, body []byte, exclude ...string) map[string]interface{} {
+	excluded := make(map[string]bool, len(SensitiveHeaders)+len(exclude))
+	for _, names := range [][]string{SensitiveHeaders, exclude} {
+		for _, name := range names {
+			excluded[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+
+	headers := make(map[string]interface{}, len(r.Header))
+	for name, values := range r.Header {
+		if excluded[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		headers[strings.ToLower(name)] = strings.Join(values, ", ")
+	}
+
+	query := make(map[string]interface{})
+	for name, values := range r.URL.Query() {
+		if len(values) > 0 {
+			query[name] = values[0]
+		}
+	}
+
+	var decoded interface{}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &decoded); err != nil {
+			decoded = string(body)
+		}
+	}
+
+	return map[string]interface{}{
+		"body":    decoded,
+		"headers": headers,
+		"query":   query,
+		"method":  r.Method,
+	}
+}
+
+// writeError responds with an error that prevented a run from starting
+func writeError(w http.ResponseWriter, err error) {
+	var inputErr *core.InputError
+	if errors.As(err, &inputErr) {
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{"error": err.Error(), "errors": inputErr.Problems})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error()})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
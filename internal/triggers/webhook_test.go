@@ -0,0 +1,166 @@
+package triggers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/carloskvasir/goflow/internal/core"
+	"github.com/carloskvasir/goflow/internal/models"
+)
+
+// newWebhookEngine cria um engine com um workflow que monta uma saudação a
+// partir do corpo, dos headers e da query da requisição
+func newWebhookEngine(t *testing.T, webhook *models.Webhook) *core.WorkflowEngine {
+	engine := core.NewWorkflowEngine()
+	workflow := &models.Workflow{
+		ID:      "greet",
+		Name:    "Greet",
+		Webhook: webhook,
+		Steps: []models.Step{
+			{
+				ID:   "greeting",
+				Type: "transform",
+				Config: map[string]interface{}{
+					"template": "{{.greeting}} {{.name}} from {{.source}}",
+					"mapping": map[string]interface{}{
+						"name":     "inputs.body.name",
+						"source":   "inputs.headers.x-source",
+						"greeting": "inputs.query.greeting",
+					},
+				},
+				Required: true,
+			},
+		},
+	}
+	if err := engine.RegisterWorkflow(workflow); err != nil {
+		t.Fatalf("Erro ao registrar workflow: %v", err)
+	}
+	return engine
+}
+
+func sendWebhook(h *Webhooks, name, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/"+name+"?greeting=hello", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Source", "tests")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	h.Handle(recorder, req, name)
+	return recorder
+}
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestWebhookSynchronousResponse(t *testing.T) {
+	engine := newWebhookEngine(t, &models.Webhook{Path: "greetings", ResponseStep: "greeting"})
+	webhooks := NewWebhooks(engine)
+
+	for _, name := range []string{"greet", "greetings"} {
+		recorder := sendWebhook(webhooks, name, `{"name": "ana"}`, nil)
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Esperava status 200 em /hooks/%s, mas obteve %d: %s", name, recorder.Code, recorder.Body)
+		}
+
+		var data string
+		if err := json.Unmarshal(recorder.Body.Bytes(), &data); err != nil {
+			t.Fatalf("Erro ao decodificar resposta: %v", err)
+		}
+		if data != "hello ana from tests" {
+			t.Errorf("Resposta inesperada: %q", data)
+		}
+		if recorder.Header().Get("X-Run-ID") == "" {
+			t.Error("Esperava o header X-Run-ID na resposta")
+		}
+	}
+
+	if recorder := sendWebhook(webhooks, "unknown", `{}`, nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("Esperava status 404 para webhook desconhecido, mas obteve %d", recorder.Code)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	engine := newWebhookEngine(t, &models.Webhook{Secret: "s3cret"})
+	webhooks := NewWebhooks(engine)
+	body := `{"name": "ana"}`
+
+	if recorder := sendWebhook(webhooks, "greet", body, nil); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Esperava status 401 sem assinatura, mas obteve %d", recorder.Code)
+	}
+
+	headers := map[string]string{DefaultSignatureHeader: sign("wrong", body)}
+	if recorder := sendWebhook(webhooks, "greet", body, headers); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Esperava status 401 com assinatura inválida, mas obteve %d", recorder.Code)
+	}
+
+	headers = map[string]string{DefaultSignatureHeader: sign("s3cret", body)}
+	recorder := sendWebhook(webhooks, "greet", body, headers)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Esperava status 202 com assinatura válida, mas obteve %d: %s", recorder.Code, recorder.Body)
+	}
+
+	var run models.Run
+	if err := json.Unmarshal(recorder.Body.Bytes(), &run); err != nil {
+		t.Fatalf("Erro ao decodificar resposta: %v", err)
+	}
+	if run.Inputs["query"].(map[string]interface{})["greeting"] != "hello" {
+		t.Errorf("Esperava a query nos inputs da execução, mas obteve %v", run.Inputs)
+	}
+}
+
+func TestWebhookDeduplication(t *testing.T) {
+	engine := newWebhookEngine(t, &models.Webhook{DeliveryIDHeader: "X-GitHub-Delivery"})
+	webhooks := NewWebhooks(engine)
+	headers := map[string]string{"X-GitHub-Delivery": "delivery-1"}
+
+	first := sendWebhook(webhooks, "greet", `{"name": "ana"}`, headers)
+	if first.Code != http.StatusAccepted {
+		t.Fatalf("Esperava status 202, mas obteve %d: %s", first.Code, first.Body)
+	}
+
+	second := sendWebhook(webhooks, "greet", `{"name": "ana"}`, headers)
+	if second.Code != http.StatusOK || !strings.Contains(second.Body.String(), `"duplicate":true`) {
+		t.Errorf("Esperava entrega duplicada, mas obteve %d: %s", second.Code, second.Body)
+	}
+
+	if runs := engine.ListRuns("greet"); len(runs) != 1 {
+		t.Errorf("Esperava 1 execução, mas obteve %d", len(runs))
+	}
+}
+
+func TestRequestInputs(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/hooks/x?a=1&a=2", strings.NewReader("plain text"))
+	req.Header.Add("X-Multi", "one")
+	req.Header.Add("X-Multi", "two")
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Cookie", "session=1")
+	req.Header.Set("X-Hub-Signature", "sha256=abc")
+
+	inputs := RequestInputs(req, []byte("plain text"), "X-Hub-Signature")
+	if inputs["body"] != "plain text" {
+		t.Errorf("Esperava o corpo em texto, mas obteve %v", inputs["body"])
+	}
+	if inputs["headers"].(map[string]interface{})["x-multi"] != "one, two" {
+		t.Errorf("Headers inesperados: %v", inputs["headers"])
+	}
+	// Credenciais e assinaturas não ficam nos inputs persistidos
+	for _, name := range []string{"authorization", "cookie", "x-hub-signature"} {
+		if _, exists := inputs["headers"].(map[string]interface{})[name]; exists {
+			t.Errorf("Header sensível %s nos inputs: %v", name, inputs["headers"])
+		}
+	}
+	if inputs["query"].(map[string]interface{})["a"] != "1" {
+		t.Errorf("Query inesperada: %v", inputs["query"])
+	}
+}